@@ -0,0 +1,3 @@
+module github.com/raffaele-99/nsight
+
+go 1.21
@@ -0,0 +1,131 @@
+package nsight
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveBases applies every signature's Base: the named signature's fields
+// are copied in under the signature's own. Scalars the signature leaves unset
+// (Category, an Info Severity, Notes, MinOpenPorts) come from the base; port lists, AnyOf
+// groups, References and Supersedes extend the base's, a base's optional
+// port becoming required if the signature requires it; Weights,
+// BannerContains and Scripts are merged, the signature's entries winning. Bases may
+// themselves have a base. A name used twice refers to its last definition,
+// as with Dedupe. Unknown bases, cycles and results that fail Validate are
+// errors. Resolved signatures have an empty Base.
+func ResolveBases(sigs []Signature) ([]Signature, error) {
+	byName := make(map[string]int, len(sigs))
+	for i, sig := range sigs {
+		byName[sig.Name] = i
+	}
+	out := append([]Signature(nil), sigs...)
+	const (
+		unvisited = iota
+		resolving
+		resolved
+	)
+	state := make([]int, len(out))
+	var resolve func(i int, chain []string) error
+	resolve = func(i int, chain []string) error {
+		sig := out[i]
+		switch {
+		case state[i] == resolved:
+			return nil
+		case state[i] == resolving:
+			return fmt.Errorf("signature %q: base cycle %s", sig.Name, strings.Join(append(chain, sig.Name), " -> "))
+		case sig.Base == "":
+			state[i] = resolved
+			return nil
+		}
+		j, ok := byName[sig.Base]
+		if !ok {
+			return fmt.Errorf("signature %q: unknown base %q", sig.Name, sig.Base)
+		}
+		state[i] = resolving
+		if err := resolve(j, append(chain, sig.Name)); err != nil {
+			return err
+		}
+		out[i] = inherit(out[j], sig)
+		if err := Validate(out[i]); err != nil {
+			return fmt.Errorf("signature %q: %w", sig.Name, err)
+		}
+		state[i] = resolved
+		return nil
+	}
+	for i := range out {
+		if err := resolve(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// inherit builds sig on top of base as described for ResolveBases.
+func inherit(base, sig Signature) Signature {
+	out := sig
+	out.Base = ""
+	if out.Category == "" {
+		out.Category = base.Category
+	}
+	if out.Severity == Info {
+		out.Severity = base.Severity
+	}
+	if out.Notes == "" {
+		out.Notes = base.Notes
+	}
+	if out.MinOpenPorts == 0 {
+		out.MinOpenPorts = base.MinOpenPorts
+	}
+	out.Required = unionPorts(base.Required, sig.Required)
+	// A base's optional port that the signature requires is promoted
+	// rather than listed twice.
+	out.Optional = nil
+	required := NewPortSet(out.Required)
+	for _, p := range unionPorts(base.Optional, sig.Optional) {
+		if !required.Has(p) {
+			out.Optional = append(out.Optional, p)
+		}
+	}
+	out.Forbidden = unionPorts(base.Forbidden, sig.Forbidden)
+	out.AnyOf = append(append([]PortGroup(nil), base.AnyOf...), sig.AnyOf...)
+	out.References = append(append([]string(nil), base.References...), sig.References...)
+	out.Supersedes = append(append([]string(nil), base.Supersedes...), sig.Supersedes...)
+	out.Weights = mergeMaps(base.Weights, sig.Weights)
+	out.BannerContains = mergeMaps(base.BannerContains, sig.BannerContains)
+	out.Scripts = mergeMaps(base.Scripts, sig.Scripts)
+	out.StrictState = base.StrictState || sig.StrictState
+	return out
+}
+
+// unionPorts returns a followed by the ports of b not already in a.
+func unionPorts(a, b PortSpec) PortSpec {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	seen := NewPortSet(a)
+	out := append(PortSpec(nil), a...)
+	for _, p := range b {
+		if !seen.Has(p) {
+			seen.Add(p)
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// mergeMaps copies a and then b into a new map, or returns nil if both are
+// empty.
+func mergeMaps[K comparable, V any](a, b map[K]V) map[K]V {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	out := make(map[K]V, len(a)+len(b))
+	for p, v := range a {
+		out[p] = v
+	}
+	for p, v := range b {
+		out[p] = v
+	}
+	return out
+}
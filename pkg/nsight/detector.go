@@ -0,0 +1,78 @@
+package nsight
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Detector finds services on a host. Port signatures are one kind; a
+// Detector can apply any logic, such as flagging hosts with an unusual
+// number of open ports.
+type Detector interface {
+	Detect(h *Host) []Result
+}
+
+// SignatureDetector is the Detector for a set of port signatures.
+type SignatureDetector []Signature
+
+// Detect runs the signatures through Match.
+func (d SignatureDetector) Detect(h *Host) []Result {
+	return Match(h, d)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Detector
+)
+
+// RegisterDetector adds d to the detectors the nsight command runs on every
+// host, alongside its signatures. It is meant to be called from init.
+func RegisterDetector(d Detector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, d)
+}
+
+// RegisteredDetectors returns the detectors added with RegisterDetector, in
+// registration order.
+func RegisteredDetectors() []Detector {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return append([]Detector(nil), registry...)
+}
+
+var (
+	signaturesMu sync.Mutex
+	signatures   = KnownSignatures()
+)
+
+// RegisterSignature adds sig to the set Signatures returns, which starts out
+// as the built-ins. A name already registered is replaced in place, so an
+// embedder can also adjust a built-in. Like RegisterDetector it is meant to be
+// called from init, and it panics if sig fails Validate.
+func RegisterSignature(sig Signature) {
+	if err := Validate(sig); err != nil {
+		panic(fmt.Sprintf("nsight: RegisterSignature %q: %v", sig.Name, err))
+	}
+	signaturesMu.Lock()
+	defer signaturesMu.Unlock()
+	signatures = Dedupe(append(signatures, sig))
+}
+
+// Signatures returns the built-in signatures followed by any added with
+// RegisterSignature. The nsight command starts from this set.
+func Signatures() []Signature {
+	signaturesMu.Lock()
+	defer signaturesMu.Unlock()
+	return append([]Signature(nil), signatures...)
+}
+
+// Detect runs each detector on h and returns all their results, in
+// detector order.
+func Detect(h *Host, detectors ...Detector) []Result {
+	var out []Result
+	for _, d := range detectors {
+		out = append(out, d.Detect(h)...)
+	}
+	return out
+}
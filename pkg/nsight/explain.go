@@ -0,0 +1,194 @@
+package nsight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Explanation traces how one signature was evaluated against a host: which
+// of its ports were open and, if it did not fire, why not.
+type Explanation struct {
+	Signature       string
+	Matched         bool
+	RequiredPresent []Port
+	RequiredMissing []Port
+	OptionalPresent []Port
+	OptionalMissing []Port
+	ForbiddenOpen   []Port
+	AnyOf           []GroupMatch
+	BannerMismatch  map[Port]string   // wanted banner text that wasn't found
+	ScriptMismatch  map[string]string // wanted NSE output, by script ID, that wasn't found
+	ScriptsNotRun   []string          // required scripts with no output at all
+	OpenPorts       int               // on the host, for MinOpenPorts
+	MinOpenPorts    int
+	Notes           string
+	References      []string
+	// Contributions breaks the confidence score down by port, in the order
+	// required, any-of groups, optional.
+	Contributions []Contribution
+}
+
+// Contribution is one port's part in a signature's confidence score: its
+// weight counts towards the total, and towards the score if it was open. An
+// any-of alternative the group didn't need has weight 0.
+type Contribution struct {
+	Port    Port
+	Role    string // "required", "optional" or the any-of group's label
+	Present bool
+	Weight  int
+}
+
+// Confidence is the score the contributions add up to, as Match computes it.
+func (e Explanation) Confidence() float64 {
+	seen, total := 0, 0
+	for _, c := range e.Contributions {
+		total += c.Weight
+		if c.Present {
+			seen += c.Weight
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(seen) / float64(total)
+}
+
+// Explain evaluates sig against h the way Match does, keeping every
+// intermediate decision.
+func Explain(h *Host, sig Signature) Explanation {
+	e := Explanation{Signature: sig.Name, Notes: sig.Notes, References: sig.References, MinOpenPorts: sig.MinOpenPorts}
+	ports := h.portsFor(sig)
+	e.OpenPorts = len(ports)
+	e.RequiredPresent, e.RequiredMissing = split(ports, sig.Required)
+	e.OptionalPresent, e.OptionalMissing = split(ports, sig.Optional)
+	e.ForbiddenOpen, _ = split(ports, sig.Forbidden)
+	groupsOK := true
+	for _, g := range sig.AnyOf {
+		present, missing := split(ports, g.Ports)
+		groupsOK = groupsOK && len(present) >= g.need()
+		e.AnyOf = append(e.AnyOf, GroupMatch{Name: g.Name, Min: g.need(), Present: present, Missing: missing})
+	}
+	for _, ports := range [][]Port{e.RequiredPresent, e.RequiredMissing, e.OptionalPresent, e.OptionalMissing, e.ForbiddenOpen} {
+		SortPorts(ports)
+	}
+	for _, p := range bannerMismatches(h, sig) {
+		if e.BannerMismatch == nil {
+			e.BannerMismatch = make(map[Port]string)
+		}
+		e.BannerMismatch[p.key()] = sig.BannerContains[p]
+	}
+	for _, id := range scriptMismatches(h, sig) {
+		if _, ran := h.ScriptOutput(id); !ran {
+			e.ScriptsNotRun = append(e.ScriptsNotRun, id)
+			continue
+		}
+		if e.ScriptMismatch == nil {
+			e.ScriptMismatch = make(map[string]string)
+		}
+		e.ScriptMismatch[id] = sig.Scripts[id]
+	}
+	e.contribute(sig, "required", e.RequiredPresent, e.RequiredMissing)
+	for _, g := range e.AnyOf {
+		label := g.Name
+		if label == "" {
+			label = "any of"
+		}
+		e.contributeGroup(sig, label, g)
+	}
+	e.contribute(sig, "optional", e.OptionalPresent, e.OptionalMissing)
+	e.Matched = len(e.RequiredMissing) == 0 && len(e.ForbiddenOpen) == 0 && groupsOK && len(e.BannerMismatch) == 0 &&
+		len(e.ScriptMismatch) == 0 && len(e.ScriptsNotRun) == 0 && e.OpenPorts >= e.MinOpenPorts
+	return e
+}
+
+// contribute adds the Contributions of one role's ports, in port order.
+func (e *Explanation) contribute(sig Signature, role string, present, missing []Port) {
+	open := NewPortSet(present)
+	ports := append(append([]Port{}, present...), missing...)
+	SortPorts(ports)
+	for _, p := range ports {
+		e.Contributions = append(e.Contributions, Contribution{Port: p, Role: role, Present: open.Has(p), Weight: sig.Weight(p)})
+	}
+}
+
+// contributeGroup is contribute for an any-of group, where only the ports
+// the group counts carry weight.
+func (e *Explanation) contributeGroup(sig Signature, role string, g GroupMatch) {
+	open, counted := NewPortSet(g.Present), NewPortSet(g.counted())
+	ports := append(append([]Port{}, g.Present...), g.Missing...)
+	SortPorts(ports)
+	for _, p := range ports {
+		c := Contribution{Port: p, Role: role, Present: open.Has(p)}
+		if counted.Has(p) {
+			c.Weight = sig.Weight(p)
+		}
+		e.Contributions = append(e.Contributions, c)
+	}
+}
+
+// Relevant reports whether any port the signature mentions was open, so
+// callers can skip signatures that had nothing to do with the host.
+func (e Explanation) Relevant() bool {
+	if e.Matched || len(e.RequiredPresent) > 0 || len(e.OptionalPresent) > 0 || len(e.ForbiddenOpen) > 0 {
+		return true
+	}
+	for _, g := range e.AnyOf {
+		if len(g.Present) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Reasons lists why the signature did not fire; it is empty for a match.
+func (e Explanation) Reasons() []string {
+	var out []string
+	if len(e.RequiredMissing) > 0 {
+		out = append(out, "required "+portString(e.RequiredMissing)+" not open")
+	}
+	for _, g := range e.AnyOf {
+		if len(g.Present) < g.Min {
+			label := g.Name
+			if label == "" {
+				label = "any-of group"
+			}
+			out = append(out, fmt.Sprintf("%s needs %d of %s, %d open", label, g.Min,
+				portString(append(append([]Port{}, g.Present...), g.Missing...)), len(g.Present)))
+		}
+	}
+	if len(e.ForbiddenOpen) > 0 {
+		out = append(out, "forbidden "+portString(e.ForbiddenOpen)+" open")
+	}
+	if e.OpenPorts < e.MinOpenPorts {
+		out = append(out, fmt.Sprintf("needs %d open ports, %d open", e.MinOpenPorts, e.OpenPorts))
+	}
+	var mismatched []Port
+	for p := range e.BannerMismatch {
+		mismatched = append(mismatched, p)
+	}
+	SortPorts(mismatched)
+	for _, p := range mismatched {
+		out = append(out, fmt.Sprintf("banner on %s lacks %q", p, e.BannerMismatch[p]))
+	}
+	var scripts []string
+	for id := range e.ScriptMismatch {
+		scripts = append(scripts, id)
+	}
+	sort.Strings(scripts)
+	for _, id := range scripts {
+		out = append(out, fmt.Sprintf("%s output lacks %q", id, e.ScriptMismatch[id]))
+	}
+	for _, id := range e.ScriptsNotRun {
+		out = append(out, "no "+id+" output")
+	}
+	return out
+}
+
+func portString(ports []Port) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ", ")
+}
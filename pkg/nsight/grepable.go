@@ -0,0 +1,51 @@
+package nsight
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseGrepableLine handles one line of nmap -oG output, e.g.
+//
+//	Host: 10.0.0.5 (dc01)	Ports: 22/open/tcp//ssh///, 445/open/tcp//microsoft-ds///
+//
+// adding its open ports to hosts. It reports whether the line was a
+// grepable "Host:" record with a Ports field.
+func parseGrepableLine(line string, hosts map[string]*Host, opts ParseOptions) bool {
+	if !strings.HasPrefix(line, "Host: ") {
+		return false
+	}
+	var host, ports string
+	found := false
+	for _, field := range strings.Split(line, "\t") {
+		if v, ok := strings.CutPrefix(field, "Host: "); ok {
+			host, _, _ = strings.Cut(strings.TrimSpace(v), " ")
+		}
+		if v, ok := strings.CutPrefix(field, "Ports: "); ok {
+			ports, found = v, true
+		}
+	}
+	if !found {
+		return false
+	}
+	for _, entry := range strings.Split(ports, ",") {
+		// port/state/protocol/owner/service/rpcinfo/version/
+		parts := strings.Split(strings.TrimSpace(entry), "/")
+		if len(parts) < 3 || !opts.counts(parts[1]) || (parts[2] != "tcp" && parts[2] != "udp") {
+			continue
+		}
+		n, err := strconv.Atoi(parts[0])
+		if err != nil || !validPort(n) {
+			if opts.Skipped != nil {
+				opts.Skipped(strings.TrimSpace(entry))
+			}
+			continue
+		}
+		banner := ""
+		if len(parts) > 6 {
+			banner = parts[4] + " " + parts[6]
+		}
+		addPort(hosts, host, Port{Number: n, Proto: parts[2]}, parts[1], banner, "")
+	}
+	return true
+}
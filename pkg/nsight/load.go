@@ -0,0 +1,282 @@
+package nsight
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalJSON accepts either a bare number (TCP) or a "161/udp" string,
+// whose protocol is case-insensitive as in a port spec.
+func (p *Port) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*p = Port{Number: n, Proto: "tcp"}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("port must be a number or \"n/proto\" string, got %s", b)
+	}
+	num, proto, _ := strings.Cut(s, "/")
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", s)
+	}
+	*p = Port{Number: n, Proto: strings.ToLower(proto)}
+	return nil
+}
+
+// UnmarshalText parses "88" or "161/udp", letting ports key JSON objects
+// such as a signature's weights.
+func (p *Port) UnmarshalText(b []byte) error {
+	ports, err := ParsePortSpec(string(b))
+	if err != nil || len(ports) != 1 {
+		return fmt.Errorf("invalid port %q", b)
+	}
+	*p = ports[0].key()
+	return nil
+}
+
+// LoadSignatures reads signatures from the JSON file at path, either a bare
+// array or a {"version": 1, "signatures": [...]} object, or from the TOML
+// equivalent of that object if path ends in .toml, and validates every entry.
+func LoadSignatures(path string) ([]Signature, error) {
+	sigs, err := readSignatures(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, sig := range sigs {
+		if err := Validate(sig); err != nil {
+			return nil, fmt.Errorf("%s: signature %d (%q): %w", path, i+1, sig.Name, err)
+		}
+	}
+	return sigs, nil
+}
+
+// Lint checks every signature in the file at path and reports all the
+// problems it finds rather than stopping at the first: fields that don't fit
+// SignatureSchema, Validate errors, Warnings, names defined more than once and
+// bases that don't resolve. Fields SignatureSchema doesn't know, such as a
+// misspelt "requried", are not problems, since loading ignores them; they
+// are listed in ignored. The error is for a file that can't be read or
+// decoded at all.
+func Lint(path string) (checked int, problems, ignored []string, err error) {
+	data, err := signatureArray(path)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	var raw []any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&raw); err != nil {
+		return 0, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	bad := make([][]string, len(raw))
+	unknown := make([][]string, len(raw))
+	for i := range raw {
+		found := schemaProblems(raw[i])
+		bad[i], unknown[i] = found.problems, found.ignored
+	}
+	where := func(i int, name string) string { return fmt.Sprintf("signature %d (%q)", i+1, name) }
+	sigs, err := decodeSignatures(path, data)
+	if err != nil {
+		// A field of the wrong shape stops the decoder, but the schema can
+		// still say which ones they are.
+		for i := range raw {
+			name, _ := lookupFold(asObject(raw[i]), "name")
+			label, _ := name.(string)
+			for _, p := range bad[i] {
+				problems = append(problems, where(i, label)+": "+p)
+			}
+			for _, p := range unknown[i] {
+				ignored = append(ignored, where(i, label)+": "+p)
+			}
+		}
+		if len(problems) == 0 {
+			return 0, nil, nil, err
+		}
+		return len(raw), problems, ignored, nil
+	}
+	// Bases may name built-ins or registered signatures as well as
+	// signatures in the file; check the file's entries as resolved.
+	if full, err := ResolveBases(append(Signatures(), sigs...)); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		sigs = full[len(full)-len(sigs):]
+	}
+	first := make(map[string]int)
+	for i, sig := range sigs {
+		for _, p := range bad[i] {
+			problems = append(problems, where(i, sig.Name)+": "+p)
+		}
+		for _, p := range unknown[i] {
+			ignored = append(ignored, where(i, sig.Name)+": "+p)
+		}
+		// Validate's error usually restates a schema problem, so it only
+		// gets a say once the fields themselves are right.
+		if err := Validate(sig); err != nil && len(bad[i]) == 0 {
+			problems = append(problems, where(i, sig.Name)+": "+err.Error())
+		}
+		for _, w := range Warnings(sig) {
+			problems = append(problems, where(i, sig.Name)+": "+w)
+		}
+		if j, ok := first[sig.Name]; ok {
+			problems = append(problems, fmt.Sprintf("%s: name already used by signature %d", where(i, sig.Name), j+1))
+		} else {
+			first[sig.Name] = i
+		}
+	}
+	return len(sigs), problems, ignored, nil
+}
+
+// signatureFileVersion is the newest signature file format this package
+// reads.
+const signatureFileVersion = 1
+
+// signatureFile is the object form of a signature file, which can carry
+// metadata alongside the list. Fields it doesn't know are ignored.
+type signatureFile struct {
+	Version    int
+	Author     string
+	Signatures json.RawMessage
+}
+
+// readSignatures decodes a signature file.
+func readSignatures(path string) ([]Signature, error) {
+	data, err := signatureArray(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSignatures(path, data)
+}
+
+func decodeSignatures(path string, data []byte) ([]Signature, error) {
+	var sigs []Signature
+	if err := json.Unmarshal(data, &sigs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sigs, nil
+}
+
+// signatureArray reads a signature file, either a bare JSON array or an
+// object with a "signatures" array, and returns the JSON array. A .toml file
+// is read as the TOML form of that object, with the signatures under
+// [[signatures]].
+func signatureArray(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		if data, err = tomlToJSON(string(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var file signatureFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if file.Version > signatureFileVersion {
+			return nil, fmt.Errorf("%s: signature file version %d is newer than this nsight supports (%d)", path, file.Version, signatureFileVersion)
+		}
+		if file.Signatures == nil {
+			return nil, fmt.Errorf("%s: no \"signatures\" array", path)
+		}
+		data = file.Signatures
+	}
+	return data, nil
+}
+
+// Validate rejects definitions that could never match sensibly.
+func Validate(sig Signature) error {
+	if strings.TrimSpace(sig.Name) == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if len(sig.Required) == 0 && len(sig.AnyOf) == 0 && sig.MinOpenPorts == 0 && sig.Base == "" {
+		return fmt.Errorf("needs required ports, an anyOf group or minOpenPorts")
+	}
+	if sig.MinOpenPorts < 0 {
+		return fmt.Errorf("minOpenPorts %d is negative", sig.MinOpenPorts)
+	}
+	ports := append(append(append([]Port{}, sig.Required...), sig.Optional...), sig.Forbidden...)
+	for i, g := range sig.AnyOf {
+		if len(g.Ports) == 0 {
+			return fmt.Errorf("anyOf group %d has no ports", i+1)
+		}
+		if g.Min < 0 || g.Min > len(g.Ports) {
+			return fmt.Errorf("anyOf group %d needs %d of only %d ports", i+1, g.Min, len(g.Ports))
+		}
+		ports = append(ports, g.Ports...)
+	}
+	for _, p := range ports {
+		if !validPort(p.Number) {
+			return fmt.Errorf("port %d out of range 1-65535", p.Number)
+		}
+		if proto := p.key().Proto; proto != "tcp" && proto != "udp" {
+			return fmt.Errorf("port %d has unknown protocol %q", p.Number, proto)
+		}
+	}
+	required := NewPortSet(sig.Required)
+	for _, p := range sig.Forbidden {
+		if required.Has(p) {
+			return fmt.Errorf("port %s is both required and forbidden", p)
+		}
+	}
+	for p, w := range sig.Weights {
+		if w < 1 {
+			return fmt.Errorf("weight %d for port %s must be positive", w, p)
+		}
+	}
+	for _, name := range sig.Supersedes {
+		if name == sig.Name {
+			return fmt.Errorf("supersedes itself")
+		}
+	}
+	for p, want := range sig.BannerContains {
+		if strings.TrimSpace(want) == "" {
+			return fmt.Errorf("bannerContains for port %s is empty", p)
+		}
+	}
+	for id := range sig.Scripts {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("scripts has an empty script ID")
+		}
+	}
+	return nil
+}
+
+// Warnings reports suspicious but usable parts of sig, such as a port listed
+// as both required and optional, which would double-count it.
+func Warnings(sig Signature) []string {
+	var out []string
+	required := NewPortSet(sig.Required)
+	for _, p := range sig.Optional {
+		if required.Has(p) {
+			out = append(out, fmt.Sprintf("port %s is both required and optional", p))
+		}
+	}
+	declared := NewPortSet(append(append([]Port{}, sig.Required...), sig.Optional...))
+	for _, g := range sig.AnyOf {
+		for _, p := range g.Ports {
+			declared.Add(p)
+		}
+	}
+	for p := range sig.Weights {
+		if !declared.Has(p) {
+			out = append(out, fmt.Sprintf("weight given for port %s, which the signature does not use", p))
+		}
+	}
+	for p := range sig.BannerContains {
+		if !declared.Has(p) {
+			out = append(out, fmt.Sprintf("bannerContains given for port %s, which the signature does not use", p))
+		}
+	}
+	return out
+}
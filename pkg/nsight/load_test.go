@@ -0,0 +1,96 @@
+package nsight
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to name in a temporary directory and returns its
+// path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSignaturesBOMAndCRLF(t *testing.T) {
+	files := map[string]string{
+		"sigs.json": "[\n  {\"name\": \"Custom\", \"required\": [8000, \"161/udp\"]}\n]\n",
+		"sigs.toml": "version = 1\n# comment\n[[signatures]]\nname = \"Custom\"\nrequired = [8000, \"161/udp\"]\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeFile(t, name, utf8BOM+strings.ReplaceAll(content, "\n", "\r\n"))
+			sigs, err := LoadSignatures(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sigs) != 1 || sigs[0].Name != "Custom" || len(sigs[0].Required) != 2 {
+				t.Fatalf("got %+v", sigs)
+			}
+			if _, problems, ignored, err := Lint(path); err != nil || len(problems)+len(ignored) > 0 {
+				t.Errorf("Lint: %v %v %v", problems, ignored, err)
+			}
+		})
+	}
+}
+
+func TestLintUnknownFieldsAreWarnings(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		problems, ignore int
+	}{
+		{"clean", `[{"name": "A", "required": [80]}]`, 0, 0},
+		{"unknown field", `[{"name": "A", "required": [80], "colour": "red"}]`, 0, 1},
+		{"unknown nested field", `[{"name": "A", "anyOf": [{"ports": [80], "mni": 1}]}]`, 0, 1},
+		// The misspelling leaves the signature without ports, which Validate
+		// reports as a problem.
+		{"misspelt required", `[{"name": "A", "requried": [80]}]`, 1, 1},
+		{"wrong type", `[{"name": "A", "required": [80], "severity": 3}]`, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, problems, ignored, err := Lint(writeFile(t, "sigs.json", tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) != tt.problems || len(ignored) != tt.ignore {
+				t.Errorf("problems %q, ignored %q; want %d and %d", problems, ignored, tt.problems, tt.ignore)
+			}
+			if _, err := LoadSignatures(writeFile(t, "sigs.json", tt.content)); (err != nil) != (tt.problems > 0) {
+				t.Errorf("LoadSignatures: %v", err)
+			}
+		})
+	}
+}
+
+func TestPortProtocolCase(t *testing.T) {
+	want := Port{161, "udp"}
+	var fromJSON, fromText Port
+	if err := json.Unmarshal([]byte(`"161/UDP"`), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromText.UnmarshalText([]byte("161/UDP")); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := ParsePortSpec("161/UDP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string]Port{"UnmarshalJSON": fromJSON, "UnmarshalText": fromText, "ParsePortSpec": spec[0]} {
+		if got != want {
+			t.Errorf("%s: got %#v, want %#v", name, got, want)
+		}
+	}
+	path := writeFile(t, "sigs.json", `[{"name": "SNMP", "required": ["161/UDP"], "weights": {"161/UDP": 2}}]`)
+	if _, err := LoadSignatures(path); err != nil {
+		t.Errorf("LoadSignatures: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package nsight
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// masscanHost is one record of masscan -oJ output.
+type masscanHost struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port   int    `json:"port"`
+		Proto  string `json:"proto"`
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+		TTL    int    `json:"ttl"`
+	} `json:"ports"`
+}
+
+// looksLikeJSON reports whether the buffered input starts with a JSON array
+// or object, which among supported formats only masscan produces.
+func looksLikeJSON(r *bufio.Reader) bool {
+	head, _ := r.Peek(512)
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && (head[0] == '[' || head[0] == '{')
+}
+
+// parseMasscanJSON collects open ports per host from masscan -oJ output.
+// Older masscan releases write one object per line with trailing commas and
+// a closing {finish: 1} record, so if the document isn't valid JSON it is
+// read again line by line.
+func parseMasscanJSON(r io.Reader) (map[string]*Host, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []masscanHost
+	if err := json.Unmarshal(data, &records); err != nil {
+		records = nil
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+			if !strings.HasPrefix(line, "{") || !strings.Contains(line, `"ip"`) {
+				continue
+			}
+			var rec masscanHost
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				return nil, fmt.Errorf("masscan record: %w", err)
+			}
+			records = append(records, rec)
+		}
+	}
+	hosts := make(map[string]*Host)
+	for _, rec := range records {
+		for _, p := range rec.Ports {
+			if p.Status == "open" && (p.Proto == "tcp" || p.Proto == "udp") && validPort(p.Port) {
+				reason := p.Reason
+				if reason != "" && p.TTL > 0 {
+					reason += fmt.Sprintf(" ttl %d", p.TTL)
+				}
+				addPort(hosts, rec.IP, Port{Number: p.Port, Proto: p.Proto}, p.Status, "", reason)
+			}
+		}
+	}
+	return hosts, nil
+}
@@ -0,0 +1,293 @@
+package nsight
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match runs sigs against the open ports of h and returns the ones whose
+// required ports are all present. Results are ordered strongest first: by
+// number of required ports, then by name, so output is stable across runs.
+func Match(h *Host, sigs []Signature) []Result {
+	var out []Result
+	for _, sig := range sigs {
+		ports := h.portsFor(sig)
+		if !hasAll(ports, sig.Required) || !hasNone(ports, sig.Forbidden) || len(ports) < sig.MinOpenPorts ||
+			len(bannerMismatches(h, sig)) > 0 || len(scriptMismatches(h, sig)) > 0 {
+			continue
+		}
+		groups, ok := matchGroups(ports, sig.AnyOf)
+		if !ok {
+			continue
+		}
+		required := append([]Port{}, sig.Required...)
+		present, missing := split(ports, sig.Optional)
+		SortPorts(required)
+		SortPorts(present)
+		SortPorts(missing)
+		r := Result{
+			Signature:       sig.Name,
+			Category:        sig.Category,
+			Severity:        sig.Severity,
+			RequiredPresent: required,
+			OptionalPresent: present,
+			OptionalMissing: missing,
+			AnyOf:           groups,
+			Confidence:      confidence(sig, present, groups),
+			Notes:           sig.Notes,
+			References:      sig.References,
+		}
+		r.Banners = lookup(h.Banners, required, r.AnyOfPresent(), present)
+		r.Reasons = lookup(h.Reasons, required, r.AnyOfPresent(), present)
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if len(out[i].RequiredPresent) != len(out[j].RequiredPresent) {
+			return len(out[i].RequiredPresent) > len(out[j].RequiredPresent)
+		}
+		return out[i].Signature < out[j].Signature
+	})
+	return out
+}
+
+// DropSuperseded removes results that another result in the same set
+// supersedes, so a host with a domain controller isn't also reported as a
+// plain SMB share. It looks at the whole set, so result order doesn't matter.
+func DropSuperseded(results []Result, sigs []Signature) []Result {
+	supersedes := make(map[string][]string, len(sigs))
+	for _, sig := range sigs {
+		supersedes[sig.Name] = sig.Supersedes
+	}
+	weaker := make(map[string]bool)
+	for _, r := range results {
+		for _, name := range supersedes[r.Signature] {
+			if name != r.Signature {
+				weaker[name] = true
+			}
+		}
+	}
+	out := make([]Result, 0, len(results))
+	for _, r := range results {
+		if !weaker[r.Signature] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Dedupe drops signatures whose name repeats an earlier one. The later
+// definition wins but keeps the earlier position, so a custom file can
+// override a built-in by reusing its name.
+func Dedupe(sigs []Signature) []Signature {
+	index := make(map[string]int, len(sigs))
+	var out []Signature
+	for _, sig := range sigs {
+		if i, ok := index[sig.Name]; ok {
+			out[i] = sig
+			continue
+		}
+		index[sig.Name] = len(out)
+		out = append(out, sig)
+	}
+	return out
+}
+
+// matchGroups checks each AnyOf group against ports. It reports false as
+// soon as a group has fewer than its minimum open.
+func matchGroups(ports PortSet, groups []PortGroup) ([]GroupMatch, bool) {
+	var out []GroupMatch
+	for _, g := range groups {
+		present, missing := split(ports, g.Ports)
+		if len(present) < g.need() {
+			return nil, false
+		}
+		SortPorts(present)
+		SortPorts(missing)
+		out = append(out, GroupMatch{Name: g.Name, Min: g.need(), Present: present, Missing: missing})
+	}
+	return out, true
+}
+
+// confidence scores a match by how much of the signature was observed: the
+// weight of the required ports plus any present optional or AnyOf ones, over
+// the weight of the required and optional ports and of the AnyOf ports each
+// group counts (see counted).
+func confidence(sig Signature, presentOptional []Port, groups []GroupMatch) float64 {
+	seen := sumWeights(sig, sig.Required) + sumWeights(sig, presentOptional)
+	total := sumWeights(sig, sig.Required) + sumWeights(sig, sig.Optional)
+	for _, g := range groups {
+		seen += sumWeights(sig, g.Present)
+		total += sumWeights(sig, g.counted())
+	}
+	if total == 0 {
+		return 1 // a MinOpenPorts-only signature: nothing else to weigh
+	}
+	return float64(seen) / float64(total)
+}
+
+// counted returns the ports of g that weigh in a confidence score: the open
+// ones and, if fewer than Min are open, enough of the missing ones, in port
+// order, to make up Min. A group needs only some of its alternatives, so the
+// rest are not held against the match.
+func (g GroupMatch) counted() []Port {
+	short := max(0, min(g.Min-len(g.Present), len(g.Missing)))
+	return append(append([]Port{}, g.Present...), g.Missing[:short]...)
+}
+
+func sumWeights(sig Signature, ports []Port) int {
+	sum := 0
+	for _, p := range ports {
+		sum += sig.Weight(p)
+	}
+	return sum
+}
+
+// lookup picks the entries of a per-port text map, such as a host's banners,
+// for the given port lists.
+func lookup(text map[Port]string, lists ...[]Port) map[Port]string {
+	var out map[Port]string
+	for _, ports := range lists {
+		for _, p := range ports {
+			if b, ok := text[p.key()]; ok {
+				if out == nil {
+					out = make(map[Port]string)
+				}
+				out[p.key()] = b
+			}
+		}
+	}
+	return out
+}
+
+// bannerMismatches returns the ports whose banner lacks the text sig's
+// BannerContains asks for, including ports with no banner at all.
+func bannerMismatches(h *Host, sig Signature) []Port {
+	var out []Port
+	for p, want := range sig.BannerContains {
+		if !strings.Contains(strings.ToLower(h.Banners[p.key()]), strings.ToLower(want)) {
+			out = append(out, p)
+		}
+	}
+	SortPorts(out)
+	return out
+}
+
+// scriptMismatches returns the IDs of scripts in sig's Scripts that didn't
+// run on h or whose output lacks the wanted text, in order.
+func scriptMismatches(h *Host, sig Signature) []string {
+	var out []string
+	for id, want := range sig.Scripts {
+		got, ran := h.ScriptOutput(id)
+		if !ran || !strings.Contains(strings.ToLower(got), strings.ToLower(want)) {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func hasAll(set PortSet, req []Port) bool {
+	for _, p := range req {
+		if !set.Has(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasNone(set PortSet, forbidden []Port) bool {
+	for _, p := range forbidden {
+		if set.Has(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// split divides ports into those open in set and those not, in one pass of
+// lookups so the cost follows the signature's size, not the host's. Both
+// slices are non-nil so they encode as [] rather than null.
+func split(set PortSet, ports []Port) (present, missing []Port) {
+	present, missing = []Port{}, []Port{}
+	for _, p := range ports {
+		if set.Has(p) {
+			present = append(present, p)
+		} else {
+			missing = append(missing, p)
+		}
+	}
+	return present, missing
+}
+
+// NearMiss is a signature that failed only because some required ports
+// were missing, e.g. one filtered by a firewall.
+type NearMiss struct {
+	Signature       string
+	RequiredPresent []Port
+	RequiredMissing []Port
+}
+
+// Coverage is the share of required ports that were open.
+func (n NearMiss) Coverage() float64 {
+	total := len(n.RequiredPresent) + len(n.RequiredMissing)
+	if total == 0 {
+		return 0
+	}
+	return float64(len(n.RequiredPresent)) / float64(total)
+}
+
+// NearMisses returns the signatures that did not match h but had at least
+// threshold (0-1) of their required ports open, best coverage first.
+// Signatures disqualified by a forbidden port are never near misses.
+func NearMisses(h *Host, sigs []Signature, threshold float64) []NearMiss {
+	var out []NearMiss
+	for _, sig := range sigs {
+		ports := h.portsFor(sig)
+		if hasAll(ports, sig.Required) || !hasNone(ports, sig.Forbidden) {
+			continue
+		}
+		present, missing := split(ports, sig.Required)
+		n := NearMiss{Signature: sig.Name, RequiredPresent: present, RequiredMissing: missing}
+		if len(present) == 0 || n.Coverage() < threshold {
+			continue
+		}
+		SortPorts(n.RequiredPresent)
+		SortPorts(n.RequiredMissing)
+		out = append(out, n)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Coverage() != out[j].Coverage() {
+			return out[i].Coverage() > out[j].Coverage()
+		}
+		return out[i].Signature < out[j].Signature
+	})
+	return out
+}
+
+// Unexplained returns the open ports of h that none of results accounts for
+// as a required, any-of or optional port, in port order. They point at
+// services no signature recognised.
+func Unexplained(h *Host, results []Result) []Port {
+	explained := make(PortSet)
+	for _, r := range results {
+		for _, p := range r.RequiredPresent {
+			explained.Add(p)
+		}
+		for _, p := range r.OptionalPresent {
+			explained.Add(p)
+		}
+		for _, g := range r.AnyOf {
+			for _, p := range g.Present {
+				explained.Add(p)
+			}
+		}
+	}
+	out := []Port{}
+	for p := range h.Ports {
+		if !explained.Has(p) {
+			out = append(out, p)
+		}
+	}
+	SortPorts(out)
+	return out
+}
@@ -0,0 +1,173 @@
+package nsight
+
+import (
+	"strings"
+	"testing"
+)
+
+// hostWith returns a host with ports open.
+func hostWith(ports ...Port) *Host {
+	h := NewHost()
+	for _, p := range ports {
+		h.Add(p, "")
+	}
+	return h
+}
+
+// names lists the signatures of results, in order.
+func names(results []Result) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.Signature
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDropSupersededDomainController(t *testing.T) {
+	h := hostWith(TCP(53, 88, 135, 139, 389, 445, 464, 636)...)
+	sigs := Signatures()
+	all := names(Match(h, sigs))
+	if !contains(all, "SMB / NetBIOS file share") {
+		t.Fatalf("SMB did not match before superseding: %v", all)
+	}
+	got := names(DropSuperseded(Match(h, sigs), sigs))
+	if !contains(got, "Active Directory Domain Controller") {
+		t.Errorf("domain controller not reported: %v", got)
+	}
+	if contains(got, "SMB / NetBIOS file share") {
+		t.Errorf("SMB share not suppressed by the domain controller: %v", got)
+	}
+}
+
+func TestMatchAnyOf(t *testing.T) {
+	withRequired := Signature{Name: "mail", Required: TCP(25), AnyOf: []PortGroup{{Name: "access", Ports: TCP(110, 143)}}}
+	groupOnly := Signature{Name: "docker", AnyOf: []PortGroup{{Ports: TCP(2375, 2376)}}}
+	tests := []struct {
+		name  string
+		sig   Signature
+		ports []Port
+		match bool
+	}{
+		{"one member present", withRequired, TCP(25, 143), true},
+		{"no member present", withRequired, TCP(25), false},
+		{"group without required ports", withRequired, TCP(110, 143), false},
+		{"group-only signature", groupOnly, TCP(2376), true},
+		{"group-only signature, nothing open", groupOnly, TCP(80), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Match(hostWith(tt.ports...), []Signature{tt.sig})
+			if (len(got) == 1) != tt.match {
+				t.Fatalf("want match %v, got %v", tt.match, got)
+			}
+			if tt.match && len(got[0].AnyOf) != 1 {
+				t.Fatalf("want one group result, got %v", got[0].AnyOf)
+			}
+		})
+	}
+	got := Match(hostWith(TCP(25, 143)...), []Signature{withRequired})
+	if g := got[0].AnyOf[0]; len(g.Present) != 1 || g.Present[0] != (Port{143, "tcp"}) || len(g.Missing) != 1 {
+		t.Errorf("group present %v, missing %v", g.Present, g.Missing)
+	}
+}
+
+func TestMatchStrictState(t *testing.T) {
+	scan := `Nmap scan report for 10.0.0.1
+PORT    STATE         SERVICE
+161/udp open|filtered snmp
+162/udp filtered      snmptrap
+`
+	hosts, err := ParseNmapReaderWith(strings.NewReader(scan), ParseOptions{IncludeFiltered: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hosts["10.0.0.1"]
+	if h == nil || !h.Filtered.Has(Port{161, "udp"}) || h.Ports.Has(Port{162, "udp"}) {
+		t.Fatalf("want 161/udp as open|filtered and 162/udp left out, got %v", h)
+	}
+	sig := Signature{Name: "snmp", Required: UDP(161), Optional: UDP(162)}
+	if got := Match(h, []Signature{sig}); len(got) != 1 || len(got[0].OptionalPresent) != 0 {
+		t.Errorf("without StrictState: want a match counting only 161/udp, got %v", got)
+	}
+	sig.StrictState = true
+	if got := Match(h, []Signature{sig}); len(got) != 0 {
+		t.Errorf("with StrictState: want no match on an open|filtered port, got %v", got)
+	}
+	h.Add(Port{161, "udp"}, "")
+	if got := Match(h, []Signature{sig}); len(got) != 1 {
+		t.Errorf("with StrictState: want a match once 161/udp is seen open, got %v", got)
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	h := NewHost()
+	for n := 1; n <= 10000; n++ {
+		h.Add(Port{Number: n, Proto: "tcp"}, "")
+	}
+	sigs := Signatures()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Match(h, sigs)
+	}
+}
+
+func TestMatchAnyOfConfidence(t *testing.T) {
+	backdoor := Signature{Name: "backdoor", AnyOf: []PortGroup{{Ports: TCP(1337, 4444, 5555, 12345, 31337)}}}
+	mail := Signature{Name: "mail", Required: TCP(25), Optional: TCP(587), AnyOf: []PortGroup{{Ports: TCP(110, 143, 993, 995)}}}
+	admin := Signature{Name: "admin", AnyOf: []PortGroup{{Ports: TCP(135, 445, 3389, 5985), Min: 2}},
+		Optional: TCP(47001), Weights: map[Port]int{{5985, "tcp"}: 3}}
+	tests := []struct {
+		name  string
+		sig   Signature
+		ports []Port
+		want  float64
+	}{
+		{"single-port any-of", backdoor, TCP(4444), 1},
+		{"required plus one alternative", mail, TCP(25, 143), 2.0 / 3},
+		{"more alternatives than needed", mail, TCP(25, 143, 993, 587), 1},
+		{"weighted alternatives", admin, TCP(445, 5985), 4.0 / 5},
+		{"three of a two-port group", admin, TCP(135, 445, 5985, 47001), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Match(hostWith(tt.ports...), []Signature{tt.sig})
+			if len(got) != 1 {
+				t.Fatalf("want a match, got %v", got)
+			}
+			if c := got[0].Confidence; c < tt.want-1e-9 || c > tt.want+1e-9 {
+				t.Errorf("confidence %.3f, want %.3f", c, tt.want)
+			}
+			if e := Explain(hostWith(tt.ports...), tt.sig); e.Confidence() != got[0].Confidence {
+				t.Errorf("Explain says %.3f, Match %.3f", e.Confidence(), got[0].Confidence)
+			}
+		})
+	}
+}
+
+func TestMatchAnyOfBannersAndReasons(t *testing.T) {
+	sig := Signature{Name: "admin", AnyOf: []PortGroup{{Ports: TCP(445, 3389, 5985), Min: 2}}}
+	h := NewHost()
+	h.Add(Port{445, "tcp"}, "microsoft-ds")
+	h.Add(Port{3389, "tcp"}, "ms-wbt-server")
+	h.SetReason(Port{445, "tcp"}, "syn-ack ttl 127")
+	h.Add(Port{22, "tcp"}, "ssh") // open, but not part of the signature
+	got := Match(h, []Signature{sig})
+	if len(got) != 1 {
+		t.Fatalf("want a match, got %v", got)
+	}
+	if b := got[0].Banners; len(b) != 2 || b[Port{445, "tcp"}] != "microsoft-ds" || b[Port{3389, "tcp"}] != "ms-wbt-server" {
+		t.Errorf("banners %v", b)
+	}
+	if r := got[0].Reasons; len(r) != 1 || r[Port{445, "tcp"}] != "syn-ack ttl 127" {
+		t.Errorf("reasons %v", r)
+	}
+}
@@ -0,0 +1,342 @@
+// Package nsight matches the open ports found by nmap against signatures of
+// composite services that span several ports.
+package nsight
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Port is a port number qualified by its transport protocol. An empty
+// Proto means TCP, so existing TCP-only definitions need not spell it out.
+type Port struct {
+	Number int
+	Proto  string
+}
+
+// key normalises p so that {445, ""} and {445, "tcp"} compare equal.
+func (p Port) key() Port {
+	if p.Proto == "" {
+		p.Proto = "tcp"
+	}
+	return p
+}
+
+// String renders TCP ports as a bare number and anything else as "161/udp".
+func (p Port) String() string {
+	if p = p.key(); p.Proto == "tcp" {
+		return strconv.Itoa(p.Number)
+	}
+	return strconv.Itoa(p.Number) + "/" + p.Proto
+}
+
+// MarshalText renders p like String, so ports can key JSON objects.
+func (p Port) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
+
+// MarshalJSON encodes TCP ports as plain numbers and others as "161/udp".
+func (p Port) MarshalJSON() ([]byte, error) {
+	if p.key().Proto == "tcp" {
+		return []byte(strconv.Itoa(p.Number)), nil
+	}
+	return json.Marshal(p.String())
+}
+
+// TCP and UDP build port lists for signature definitions.
+func TCP(nums ...int) []Port { return portsOf("tcp", nums) }
+func UDP(nums ...int) []Port { return portsOf("udp", nums) }
+
+func portsOf(proto string, nums []int) []Port {
+	ports := make([]Port, len(nums))
+	for i, n := range nums {
+		ports[i] = Port{Number: n, Proto: proto}
+	}
+	return ports
+}
+
+// SortPorts orders ports by number, then protocol.
+func SortPorts(ports []Port) {
+	sort.Slice(ports, func(i, j int) bool {
+		a, b := ports[i].key(), ports[j].key()
+		if a.Number != b.Number {
+			return a.Number < b.Number
+		}
+		return a.Proto < b.Proto
+	})
+}
+
+// PortSet is the set of open ports seen on one host.
+type PortSet map[Port]struct{}
+
+// Add inserts p, normalising its protocol.
+func (s PortSet) Add(p Port) { s[p.key()] = struct{}{} }
+
+// Has reports whether p is in the set.
+func (s PortSet) Has(p Port) bool {
+	_, ok := s[p.key()]
+	return ok
+}
+
+// NewPortSet builds a set from a port list.
+func NewPortSet(ports []Port) PortSet {
+	set := make(PortSet, len(ports))
+	for _, p := range ports {
+		set.Add(p)
+	}
+	return set
+}
+
+// Host is what a scan recorded about one address.
+type Host struct {
+	Ports   PortSet
+	Banners map[Port]string // service/version text from -sV, where nmap printed one
+	Reasons map[Port]string // why nmap called the port open (--reason), e.g. "syn-ack ttl 127"
+	// Filtered holds the ports in Ports that nmap only reported as
+	// open|filtered, which are recorded when parsing with IncludeFiltered.
+	Filtered PortSet
+	// Scripts holds NSE output (--script, -sC) by port and then script ID.
+	// Host script results, such as smb-os-discovery, are under HostScripts.
+	Scripts map[Port]map[string]string
+	// Started and Finished are when the scan that reported the host began
+	// and ended, from the -oN/-oG header and footer or the -oX run times.
+	// Either is zero when the input didn't record it.
+	Started, Finished time.Time
+}
+
+// HostScripts is the Scripts key for nmap's "Host script results", which
+// belong to the host rather than a port.
+var HostScripts = Port{}
+
+// NewHost returns a Host with no open ports.
+func NewHost() *Host {
+	return &Host{Ports: make(PortSet), Banners: make(map[Port]string), Reasons: make(map[Port]string), Filtered: make(PortSet),
+		Scripts: make(map[Port]map[string]string)}
+}
+
+// Add records p as open along with its banner, if any. A port reported
+// twice keeps the first non-empty banner.
+func (h *Host) Add(p Port, banner string) {
+	h.Ports.Add(p)
+	delete(h.Filtered, p.key())
+	if _, seen := h.Banners[p.key()]; banner != "" && !seen {
+		h.Banners[p.key()] = banner
+	}
+}
+
+// AddFiltered records p as open|filtered. A port already seen as open stays
+// confirmed.
+func (h *Host) AddFiltered(p Port, banner string) {
+	if !h.Ports.Has(p) {
+		h.Filtered.Add(p)
+	}
+	h.Ports.Add(p)
+	if _, seen := h.Banners[p.key()]; banner != "" && !seen {
+		h.Banners[p.key()] = banner
+	}
+}
+
+// Remove forgets p along with its banner, reason and script output.
+func (h *Host) Remove(p Port) {
+	p = p.key()
+	delete(h.Ports, p)
+	delete(h.Filtered, p)
+	delete(h.Banners, p)
+	delete(h.Reasons, p)
+	delete(h.Scripts, p)
+}
+
+// portsFor returns the open ports sig may consider: all of them, or for a
+// StrictState signature only those nmap confirmed as open.
+func (h *Host) portsFor(sig Signature) PortSet {
+	if !sig.StrictState || len(h.Filtered) == 0 {
+		return h.Ports
+	}
+	confirmed := make(PortSet, len(h.Ports))
+	for p := range h.Ports {
+		if !h.Filtered.Has(p) {
+			confirmed.Add(p)
+		}
+	}
+	return confirmed
+}
+
+// SetReason records nmap's reason for p being open, keeping the first one
+// seen.
+func (h *Host) SetReason(p Port, reason string) {
+	if _, seen := h.Reasons[p.key()]; reason != "" && !seen {
+		h.Reasons[p.key()] = reason
+	}
+}
+
+// Reason returns nmap's reason for p being open, or "" if it gave none. A
+// port with no protocol is looked up as TCP.
+func (h *Host) Reason(p Port) string {
+	return h.Reasons[p.key()]
+}
+
+// AddScript records the output of NSE script id for p, or for the host when
+// p is HostScripts, keeping the first output seen.
+func (h *Host) AddScript(p Port, id, output string) {
+	if p != HostScripts {
+		p = p.key()
+	}
+	if h.Scripts[p] == nil {
+		h.Scripts[p] = make(map[string]string)
+	}
+	if _, seen := h.Scripts[p][id]; !seen {
+		h.Scripts[p][id] = output
+	}
+}
+
+// ScriptOutput returns everything script id printed on the host, on any
+// port or as a host script, and whether it ran at all.
+func (h *Host) ScriptOutput(id string) (string, bool) {
+	var outputs []string
+	for _, scripts := range h.Scripts {
+		if out, ok := scripts[id]; ok {
+			outputs = append(outputs, out)
+		}
+	}
+	sort.Strings(outputs)
+	return strings.Join(outputs, "\n"), len(outputs) > 0
+}
+
+// Merge folds the ports, banners, reasons and script output of other into h,
+// widening h's scan times to cover other's.
+func (h *Host) Merge(other *Host) {
+	for p := range other.Ports {
+		if other.Filtered.Has(p) {
+			h.AddFiltered(p, other.Banners[p])
+		} else {
+			h.Add(p, other.Banners[p])
+		}
+		h.SetReason(p, other.Reasons[p])
+	}
+	for p, scripts := range other.Scripts {
+		for id, out := range scripts {
+			h.AddScript(p, id, out)
+		}
+	}
+	if !other.Started.IsZero() && (h.Started.IsZero() || other.Started.Before(h.Started)) {
+		h.Started = other.Started
+	}
+	if other.Finished.After(h.Finished) {
+		h.Finished = other.Finished
+	}
+}
+
+// Signature for a composite service. It fires when every Required port is
+// open, every AnyOf group is satisfied and no Forbidden port is open. The
+// conditions are combined with AND: AnyOf never relaxes Required, and a
+// Forbidden port vetoes the match whatever else is open. A port may not be
+// both required and forbidden.
+type Signature struct {
+	Name      string
+	Category  string   // e.g. "Databases" or "Windows"; used to group output
+	Severity  Severity // how much a match matters; Info if unset
+	Required  PortSpec
+	Optional  PortSpec
+	Forbidden PortSpec
+	// AnyOf lists groups of alternative ports; each group needs at least Min
+	// (default 1) open ports, on top of everything in Required.
+	AnyOf []PortGroup
+	// Weights marks some ports as more diagnostic than others when scoring
+	// confidence. Ports not listed weigh 1.
+	Weights map[Port]int
+	// BannerContains requires the -sV banner of a port to contain some text
+	// (ignoring case), for services that ports alone can't tell apart. A
+	// host scanned without -sV never satisfies it.
+	BannerContains map[Port]string
+	// Notes and References point the analyst at what to check next, such as
+	// hardening guidance or known weaknesses. They don't affect matching.
+	Notes      string
+	References []string
+	// StrictState ignores ports nmap only reported as open|filtered, even
+	// when the scan was parsed with IncludeFiltered (--include-filtered).
+	StrictState bool
+	// Supersedes names weaker signatures this one implies, e.g. a domain
+	// controller implies an SMB share. See DropSuperseded.
+	Supersedes []string
+	// Scripts requires NSE script output, by script ID, containing some
+	// text (ignoring case) anywhere on the host; empty text only requires the
+	// script to have printed something. A scan without --script or -sC never
+	// satisfies it.
+	Scripts map[string]string
+	// MinOpenPorts, if set, only lets the signature fire on a host with at
+	// least this many open ports, whichever they are. With no port lists it
+	// makes a heuristic of its own, such as flagging likely honeypots.
+	MinOpenPorts int
+	// Base names a signature to build on; see ResolveBases. A signature
+	// with a base may leave out Required and AnyOf.
+	Base string
+	// Description says what the signature is for, for whoever maintains the
+	// file. It doesn't affect matching or output.
+	Description string
+}
+
+// PortGroup is a set of alternative ports, such as the mail access
+// protocols a mail server may expose alongside SMTP.
+type PortGroup struct {
+	Name  string // short label for output, e.g. "mail access"
+	Ports PortSpec
+	Min   int // ports that must be open; 0 means 1
+}
+
+// need returns how many of g's ports must be open.
+func (g PortGroup) need() int {
+	if g.Min < 1 {
+		return 1
+	}
+	return g.Min
+}
+
+// Weight returns how much p counts towards sig's confidence.
+func (sig Signature) Weight(p Port) int {
+	if w, ok := sig.Weights[p.key()]; ok {
+		return w
+	}
+	if w, ok := sig.Weights[Port{Number: p.Number}]; ok && p.key().Proto == "tcp" {
+		return w
+	}
+	return 1
+}
+
+// GroupMatch records which ports of a PortGroup were open.
+type GroupMatch struct {
+	Name    string `json:"name,omitempty"`
+	Min     int    `json:"min"`
+	Present []Port `json:"present"`
+	Missing []Port `json:"missing"`
+}
+
+// Result is the structured outcome of one signature firing against a host.
+// It carries no presentation; the CLI renders text, JSON and Markdown from it.
+type Result struct {
+	Signature       string   `json:"signature"`
+	Category        string   `json:"category,omitempty"`
+	Severity        Severity `json:"severity"`
+	RequiredPresent []Port   `json:"required"`
+	OptionalPresent []Port   `json:"optionalPresent"`
+	OptionalMissing []Port   `json:"optionalMissing"`
+	// AnyOf reports, per AnyOf group of the signature, which ports were seen.
+	AnyOf      []GroupMatch `json:"anyOf,omitempty"`
+	Confidence float64      `json:"confidence"`
+	// Banners holds the -sV service text for matched ports that had one.
+	Banners map[Port]string `json:"banners,omitempty"`
+	// Reasons holds nmap's --reason annotation for matched ports that had one.
+	Reasons    map[Port]string `json:"reasons,omitempty"`
+	Notes      string          `json:"notes,omitempty"`
+	References []string        `json:"references,omitempty"`
+}
+
+// AnyOfPresent returns the open ports of all r's AnyOf groups, in port order.
+func (r Result) AnyOfPresent() []Port {
+	var out []Port
+	for _, g := range r.AnyOf {
+		out = append(out, g.Present...)
+	}
+	SortPorts(out)
+	return out
+}
@@ -0,0 +1,397 @@
+package nsight
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotNmap is returned in strict mode for input with no nmap structure.
+var ErrNotNmap = errors.New("no nmap output found")
+
+// ParseOptions adjusts how ParseNmapReaderWith reads a scan. The zero value
+// behaves like ParseNmapReader.
+type ParseOptions struct {
+	// Strict fails with ErrNotNmap when -oN/-oG input contains nothing that
+	// looks like nmap output, which usually means the wrong file was given.
+	Strict bool
+	// Skipped, if set, is called with each -oN line that looks like a port
+	// entry but was not counted as open, such as a filtered port, and with
+	// -oN or -oG entries whose port number is outside 1-65535.
+	Skipped func(line string)
+	// IncludeFiltered counts "open|filtered" ports as open. UDP scans report
+	// most listening services this way.
+	IncludeFiltered bool
+}
+
+// counts reports whether a port in the given nmap state counts as open.
+func (o ParseOptions) counts(state string) bool {
+	state = strings.ToLower(state)
+	return state == "open" || (o.IncludeFiltered && state == "open|filtered")
+}
+
+// ParseNmapReader sniffs the format of rd (nmap -oX, -oG or -oN, or masscan
+// -oJ, optionally gzipped) and collects open ports per host. In -oN output,
+// ports listed before any "Nmap scan report for" line are keyed by "".
+func ParseNmapReader(rd io.Reader) (map[string]*Host, error) {
+	return ParseNmapReaderWith(rd, ParseOptions{})
+}
+
+// ParseNmapReaderWith is ParseNmapReader with options.
+func ParseNmapReaderWith(rd io.Reader, opts ParseOptions) (map[string]*Host, error) {
+	r := bufio.NewReader(rd)
+	if head, _ := r.Peek(len(gzipMagic)); string(head) == gzipMagic {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = bufio.NewReader(zr)
+	}
+	// Files saved on Windows may start with a UTF-8 byte order mark, which
+	// would hide the format markers below. CRLF endings need nothing special:
+	// lines are trimmed, "\r" included.
+	if head, _ := r.Peek(len(utf8BOM)); string(head) == utf8BOM {
+		r.Discard(len(utf8BOM))
+	}
+	if head, _ := r.Peek(len(xmlHeader)); string(head) == xmlHeader {
+		return parseNmapXML(r, opts)
+	}
+	if looksLikeJSON(r) {
+		return parseMasscanJSON(r)
+	}
+
+	hosts := make(map[string]*Host)
+	host := ""
+	recognised := false
+	var nse nseBlock
+	var started, finished time.Time
+	// Lines are read whole however long they are: -sV script output can put
+	// megabytes on one line, beyond what a bufio.Scanner token allows.
+	for {
+		raw, err := r.ReadString('\n')
+		if err == io.EOF && raw == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line := strings.TrimSpace(raw)
+		if parseGrepableLine(line, hosts, opts) {
+			recognised = true
+			continue
+		}
+		if m := startedLine.FindStringSubmatch(line); m != nil {
+			started = nmapTime(m[1])
+		}
+		if m := finishedLine.FindStringSubmatch(line); m != nil {
+			finished = nmapTime(m[1])
+		}
+		// nmap's own bookkeeping never describes ports, however many digits
+		// or protocol names it holds.
+		if summaryLine.MatchString(line) {
+			nse.flush(hosts)
+			nse.owner = nil
+			recognised = true
+			continue
+		}
+		if m := reportLine.FindStringSubmatch(line); m != nil {
+			nse.flush(hosts)
+			host = hostAddr(m[1])
+			nse.owner = nil
+			recognised = true
+			continue
+		}
+		if m := portLine.FindStringSubmatch(line); m != nil && opts.counts(m[3]) {
+			if p, err := strconv.Atoi(m[1]); err == nil && validPort(p) {
+				nse.flush(hosts)
+				port := Port{Number: p, Proto: strings.ToLower(m[2])}
+				banner, reason := splitReason(m[4])
+				addPort(hosts, host, port, m[3], banner, reason)
+				nse.owner = &nseOwner{host, port}
+				recognised = true
+				continue
+			}
+		}
+		// Anything else shaped like a port entry, including open ports with
+		// an impossible number, is skipped.
+		if portLike.MatchString(line) {
+			nse.flush(hosts)
+			nse.owner = nil
+			if opts.Skipped != nil {
+				opts.Skipped(line)
+			}
+			recognised = true
+			continue
+		}
+		if line == "Host script results:" {
+			nse.flush(hosts)
+			nse.owner = &nseOwner{host, HostScripts}
+			recognised = true
+			continue
+		}
+		if nse.add(line, hosts) {
+			continue
+		}
+		nse.flush(hosts)
+		recognised = recognised || nmapMarker.MatchString(line)
+	}
+	nse.flush(hosts)
+	if opts.Strict && !recognised {
+		return nil, ErrNotNmap
+	}
+	stamp(hosts, started, finished)
+	return hosts, nil
+}
+
+// startedLine and finishedLine are the -oN and -oG header and footer, e.g.
+// "# Nmap 7.94 scan initiated Mon Oct 13 09:12:01 2026 as: nmap ..." and
+// "# Nmap done at Mon Oct 13 09:13:37 2026 -- 2 IP addresses ...".
+var (
+	startedLine  = regexp.MustCompile(`^# Nmap \S+ scan initiated (.+?) as: `)
+	finishedLine = regexp.MustCompile(`^# Nmap done at (.+?) -- `)
+)
+
+// nmapTime parses a header or footer timestamp, which nmap writes in the
+// scanning machine's local time without a zone; it is read as local time
+// here. An unrecognised timestamp gives the zero time.
+func nmapTime(s string) time.Time {
+	t, err := time.ParseInLocation(time.ANSIC, s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// stamp records the scan's start and end on every host it reported.
+func stamp(hosts map[string]*Host, started, finished time.Time) {
+	for _, h := range hosts {
+		h.Started, h.Finished = started, finished
+	}
+}
+
+// nseOwner is what the NSE output being read belongs to: a port, or the
+// host for HostScripts.
+type nseOwner struct {
+	host string
+	port Port
+}
+
+// nseBlock collects NSE output in -oN, which follows its port line (or
+// "Host script results:") as "| id: text", continued on "|   more" lines
+// and closed by a line starting "|_".
+type nseBlock struct {
+	owner *nseOwner // nil outside an open port or host script section
+	id    string
+	lines []string
+}
+
+// nseStart matches the first line of one script's output: "| id:" or, for
+// one-line output, "|_id:". Continuation lines are indented further.
+var nseStart = regexp.MustCompile(`^\|[ _]([A-Za-z0-9][\w.-]*):(?:[\s\p{Zs}]+(.*))?$`)
+
+// add consumes line if it is NSE output, reporting whether it was.
+func (b *nseBlock) add(line string, hosts map[string]*Host) bool {
+	if b.owner == nil || !strings.HasPrefix(line, "|") {
+		return false
+	}
+	if m := nseStart.FindStringSubmatch(line); m != nil {
+		b.flush(hosts)
+		b.id, b.lines = m[1], []string{m[2]}
+	} else if b.id != "" {
+		b.lines = append(b.lines, strings.TrimSpace(strings.TrimLeft(line, "|_")))
+	}
+	if strings.HasPrefix(line, "|_") {
+		b.flush(hosts)
+	}
+	return true
+}
+
+// flush records the script being read, if any. Output for a host with no
+// open ports is dropped along with the host.
+func (b *nseBlock) flush(hosts map[string]*Host) {
+	if b.id != "" && b.owner != nil && hosts[b.owner.host] != nil {
+		hosts[b.owner.host].AddScript(b.owner.port, b.id, strings.TrimSpace(strings.Join(b.lines, "\n")))
+	}
+	b.id, b.lines = "", nil
+}
+
+// portLine matches an open or open|filtered port in the -oN port table. It tolerates tabs,
+// non-breaking spaces and leading table borders ("| ", "│ ", "> ") that
+// creep in when output is pasted through other tools.
+var portLine = regexp.MustCompile(`(?i)^[|│>*\s\p{Zs}]*(\d+)/(tcp|udp)[\s\p{Zs}]+(open(?:\|filtered)?)(?:[\s\p{Zs}]+(.*))?$`)
+
+// reportLine starts a host's section in -oN output.
+var reportLine = regexp.MustCompile(`^Nmap scan report for (.+)$`)
+
+// portLike matches any port table entry, whatever its state.
+var portLike = regexp.MustCompile(`^[|│>*\s\p{Zs}]*\d+/\w+[\s\p{Zs}]`)
+
+// summaryLine matches the lines nmap writes around a port table that count
+// or head ports rather than list them, e.g. "Not shown: 995 closed tcp ports
+// (reset)" or "# Ports scanned: TCP(1000;1,3-4,...)".
+var summaryLine = regexp.MustCompile(`^(Not shown: |All \d+ scanned ports |PORT[\s\p{Zs}]+STATE|#?[\s\p{Zs}]*Ports scanned: |Some closed ports may be reported as filtered)`)
+
+// nmapMarker matches lines nmap writes around its results.
+var nmapMarker = regexp.MustCompile(`^(# Nmap |Starting Nmap |Nmap done:|Host: |PORT[\s\p{Zs}]+STATE)`)
+
+// hostAddr extracts the address from a report target such as
+// "dc01.corp.local (10.0.0.5)" or a bare "10.0.0.5". IPv6 literals, zone
+// included, are kept whole: "dc6 (2001:db8::1)" gives "2001:db8::1".
+func hostAddr(target string) string {
+	if i := strings.LastIndex(target, " ("); i >= 0 && strings.HasSuffix(target, ")") {
+		return target[i+2 : len(target)-1]
+	}
+	return target
+}
+
+// validPort reports whether n is a usable port number.
+func validPort(n int) bool {
+	return n >= 1 && n <= 65535
+}
+
+func addPort(hosts map[string]*Host, host string, p Port, state, banner, reason string) {
+	if hosts[host] == nil {
+		hosts[host] = NewHost()
+	}
+	banner = strings.Join(strings.Fields(banner), " ")
+	if strings.EqualFold(state, "open|filtered") {
+		hosts[host].AddFiltered(p, banner)
+	} else {
+		hosts[host].Add(p, banner)
+	}
+	hosts[host].SetReason(p, strings.Join(strings.Fields(reason), " "))
+}
+
+// reasonColumn matches the text after the state in a port line written with
+// --reason: the service, then the reason and its TTL, then any version.
+var reasonColumn = regexp.MustCompile(`^(\S+)[\s\p{Zs}]+((?:syn-ack|udp-response|proto-response|user-set|echo-reply|init-ack|no-response)(?:[\s\p{Zs}]+ttl[\s\p{Zs}]+\d+)?)(?:[\s\p{Zs}]+(.*))?$`)
+
+// splitReason separates nmap's reason column, if present, from the banner.
+func splitReason(text string) (banner, reason string) {
+	m := reasonColumn.FindStringSubmatch(text)
+	if m == nil {
+		return text, ""
+	}
+	return m[1] + " " + m[3], m[2]
+}
+
+const (
+	xmlHeader = "<?xml"
+	gzipMagic = "\x1f\x8b"
+	utf8BOM   = "\xef\xbb\xbf"
+)
+
+// nmapRun mirrors the parts of an nmap -oX document we care about.
+type nmapRun struct {
+	Start    int64 `xml:"start,attr"`
+	Finished struct {
+		Time int64 `xml:"time,attr"`
+	} `xml:"runstats>finished"`
+	Hosts []struct {
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   int    `xml:"portid,attr"`
+			State    struct {
+				State     string `xml:"state,attr"`
+				Reason    string `xml:"reason,attr"`
+				ReasonTTL string `xml:"reason_ttl,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name      string `xml:"name,attr"`
+				Product   string `xml:"product,attr"`
+				Version   string `xml:"version,attr"`
+				ExtraInfo string `xml:"extrainfo,attr"`
+			} `xml:"service"`
+			Scripts []nmapScript `xml:"script"`
+		} `xml:"ports>port"`
+		HostScripts []nmapScript `xml:"hostscript>script"`
+	} `xml:"host"`
+}
+
+type nmapScript struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+// parseNmapXML collects open TCP and UDP ports per host from nmap -oX output.
+func parseNmapXML(r io.Reader, opts ParseOptions) (map[string]*Host, error) {
+	var run nmapRun
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]*Host)
+	for _, h := range run.Hosts {
+		host := ""
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+				host = a.Addr
+				break
+			}
+		}
+		for _, p := range h.Ports {
+			if (p.Protocol == "tcp" || p.Protocol == "udp") && opts.counts(p.State.State) && validPort(p.PortID) {
+				sv := p.Service
+				banner := sv.Name + " " + sv.Product + " " + sv.Version + " " + sv.ExtraInfo
+				reason := p.State.Reason
+				if reason != "" && p.State.ReasonTTL != "" {
+					reason += " ttl " + p.State.ReasonTTL
+				}
+				port := Port{Number: p.PortID, Proto: p.Protocol}
+				addPort(hosts, host, port, p.State.State, banner, reason)
+				for _, sc := range p.Scripts {
+					hosts[host].AddScript(port, sc.ID, strings.TrimSpace(sc.Output))
+				}
+			}
+		}
+		if hosts[host] != nil {
+			for _, sc := range h.HostScripts {
+				hosts[host].AddScript(HostScripts, sc.ID, strings.TrimSpace(sc.Output))
+			}
+		}
+	}
+	stamp(hosts, unixTime(run.Start), unixTime(run.Finished.Time))
+	return hosts, nil
+}
+
+// unixTime converts an -oX time attribute, leaving one that is missing as
+// the zero time.
+func unixTime(sec int64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// SortedHosts orders host keys by IP address, IPv4 before IPv6, falling back
+// to plain string order for names that are not addresses.
+func SortedHosts(hosts map[string]*Host) []string {
+	keys := make([]string, 0, len(hosts))
+	for h := range hosts {
+		keys = append(keys, h)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := netip.ParseAddr(keys[i])
+		b, errB := netip.ParseAddr(keys[j])
+		if errA == nil && errB == nil {
+			return a.Compare(b) < 0
+		}
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,252 @@
+package nsight
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const normalSample = `# Nmap 7.94 scan initiated Mon Oct 13 10:00:00 2026 as: nmap -sV -oN dc.nmap 10.0.0.5
+Nmap scan report for dc01.corp.local (10.0.0.5)
+Host is up (0.0010s latency).
+Not shown: 995 closed tcp ports (reset)
+PORT    STATE SERVICE      VERSION
+88/tcp  open  kerberos-sec Microsoft Windows Kerberos
+445/tcp open  microsoft-ds
+| smb-security-mode:
+|_  message_signing: required
+161/udp open  snmp
+# Nmap done at Mon Oct 13 10:01:00 2026 -- 1 IP address (1 host up) scanned in 60.00 seconds
+`
+
+const xmlSample = `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" start="1760349600" version="7.94">
+<host><address addr="10.0.0.5" addrtype="ipv4"/>
+<ports>
+<port protocol="tcp" portid="445"><state state="open" reason="syn-ack"/><service name="microsoft-ds"/></port>
+<port protocol="udp" portid="161"><state state="open" reason="udp-response"/></port>
+</ports></host>
+<runstats><finished time="1760349660"/></runstats>
+</nmaprun>
+`
+
+func FuzzParseNmapReader(f *testing.F) {
+	crlf := strings.ReplaceAll(normalSample, "\n", "\r\n")
+	for _, seed := range []string{
+		normalSample,
+		xmlSample,
+		utf8BOM + crlf,
+		utf8BOM + xmlSample,
+		normalSample[:len(normalSample)/2],
+		xmlSample[:len(xmlSample)/2],
+		"Host: 10.0.0.5 ()\tPorts: 445/open/tcp//microsoft-ds///\n",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		hosts, err := ParseNmapReader(strings.NewReader(string(data)))
+		if err != nil {
+			return
+		}
+		for _, h := range hosts {
+			for p := range h.Ports {
+				if !validPort(p.Number) {
+					t.Fatalf("parsed out-of-range port %v", p)
+				}
+			}
+		}
+	})
+}
+
+func TestParseMessyPortLines(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Port // zero when the line must not count
+	}{
+		{"plain", "445/tcp open  microsoft-ds", Port{445, "tcp"}},
+		{"tabs", "445/tcp\topen\tmicrosoft-ds", Port{445, "tcp"}},
+		{"leading pipe", "| 80/tcp open http", Port{80, "tcp"}},
+		{"box drawing", "│ 443/tcp │ open │ https", Port{}},
+		{"box drawing prefix", "│ 443/tcp open https", Port{443, "tcp"}},
+		{"no-break spaces", "8080/tcp\u00a0open\u00a0http-proxy", Port{8080, "tcp"}},
+		{"quoted", "> 22/tcp open ssh", Port{22, "tcp"}},
+		{"starred", "* 3389/tcp open ms-wbt-server", Port{3389, "tcp"}},
+		{"indented udp", "   161/udp open snmp", Port{161, "udp"}},
+		{"upper case", "53/TCP OPEN domain", Port{53, "tcp"}},
+		{"no service", "6379/tcp open", Port{6379, "tcp"}},
+		{"filtered", "135/tcp filtered msrpc", Port{}},
+		{"closed", "23/tcp closed telnet", Port{}},
+		{"header", "PORT     STATE SERVICE", Port{}},
+		{"out of range", "70000/tcp open unknown", Port{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, err := ParseNmapReader(strings.NewReader(tt.line + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := hosts[""]
+			if tt.want == (Port{}) {
+				if h != nil && len(h.Ports) > 0 {
+					t.Errorf("%q counted ports %v", tt.line, h.Ports)
+				}
+				return
+			}
+			if h == nil || !h.Ports.Has(tt.want) || len(h.Ports) != 1 {
+				t.Errorf("%q: want only %v open, got %v", tt.line, tt.want, h)
+			}
+		})
+	}
+}
+
+func TestParseIPv6Hosts(t *testing.T) {
+	normal := `Nmap scan report for 2001:db8::5
+PORT   STATE SERVICE
+22/tcp open  ssh
+Nmap scan report for web.example.com (2001:db8::80)
+PORT    STATE SERVICE
+443/tcp open  https
+`
+	xml := `<?xml version="1.0"?>
+<nmaprun>
+<host><address addr="2001:db8::5" addrtype="ipv6"/><address addr="00:11:22:33:44:55" addrtype="mac"/>
+<ports><port protocol="tcp" portid="22"><state state="open"/></port></ports></host>
+</nmaprun>
+`
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]Port
+	}{
+		{"-oN", normal, map[string]Port{"2001:db8::5": {22, "tcp"}, "2001:db8::80": {443, "tcp"}}},
+		{"-oX", xml, map[string]Port{"2001:db8::5": {22, "tcp"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, err := ParseNmapReader(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hosts) != len(tt.want) {
+				t.Fatalf("want hosts %v, got %v", tt.want, SortedHosts(hosts))
+			}
+			for addr, p := range tt.want {
+				if h := hosts[addr]; h == nil || !h.Ports.Has(p) {
+					t.Errorf("%s: want %v open, got %v", addr, p, h)
+				}
+			}
+		})
+	}
+}
+
+func TestParseVeryLongLine(t *testing.T) {
+	long := strings.Repeat("A", 4<<20) // far past bufio.Scanner's 64 KiB token limit
+	scan := "Nmap scan report for 10.0.0.1\n" +
+		"80/tcp open  http\n" +
+		"|_http-title: " + long + "\n" +
+		"443/tcp open  https " + long + "\n" +
+		"8080/tcp open  http-proxy\n"
+	hosts, err := ParseNmapReader(strings.NewReader(scan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hosts["10.0.0.1"]
+	if h == nil || len(h.Ports) != 3 {
+		t.Fatalf("want 80, 443 and 8080 open, got %v", h)
+	}
+	if out := h.Scripts[Port{80, "tcp"}]["http-title"]; len(out) != len(long) {
+		t.Errorf("http-title output is %d bytes, want %d", len(out), len(long))
+	}
+}
+
+func TestParseBOMAndCRLF(t *testing.T) {
+	for name, input := range map[string]string{
+		"-oN": utf8BOM + strings.ReplaceAll(normalSample, "\n", "\r\n"),
+		"-oX": utf8BOM + strings.ReplaceAll(xmlSample, "\n", "\r\n"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			hosts, err := ParseNmapReaderWith(strings.NewReader(input), ParseOptions{Strict: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := hosts["10.0.0.5"]
+			if h == nil || !h.Ports.Has(Port{445, "tcp"}) || !h.Ports.Has(Port{161, "udp"}) {
+				t.Fatalf("want 445/tcp and 161/udp on 10.0.0.5, got %v", hosts)
+			}
+			if h.Started.IsZero() || h.Finished.IsZero() {
+				t.Errorf("scan times not read: %v, %v", h.Started, h.Finished)
+			}
+		})
+	}
+}
+
+func TestParseRealisticNormalOutput(t *testing.T) {
+	f, err := os.Open("testdata/full.nmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var skipped []string
+	opts := ParseOptions{Strict: true, Skipped: func(line string) { skipped = append(skipped, line) }}
+	hosts, err := ParseNmapReaderWith(f, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]Port{
+		"10.0.0.5": TCP(53, 88, 135, 139, 389, 445, 464, 593, 636, 3268),
+		"10.0.0.7": TCP(22, 139, 445),
+	}
+	if len(hosts) != len(want) {
+		t.Fatalf("want hosts 10.0.0.5 and 10.0.0.7, got %v", SortedHosts(hosts))
+	}
+	for addr, ports := range want {
+		h := hosts[addr]
+		if h == nil || len(h.Ports) != len(ports) {
+			t.Errorf("%s: want %v, got %v", addr, ports, h)
+			continue
+		}
+		for _, p := range ports {
+			if !h.Ports.Has(p) {
+				t.Errorf("%s: %v not open", addr, p)
+			}
+		}
+	}
+	// Only the filtered and closed port entries are skipped; "Not shown",
+	// the table header, Service Info and NSE lines are not port entries.
+	if len(skipped) != 2 {
+		t.Errorf("want the filtered and closed ports skipped, got %q", skipped)
+	}
+	dc := hosts["10.0.0.5"]
+	if got := dc.Reasons[Port{88, "tcp"}]; got != "syn-ack ttl 128" {
+		t.Errorf("88/tcp reason %q", got)
+	}
+	if _, ok := dc.ScriptOutput("smb2-security-mode"); !ok {
+		t.Error("host script smb2-security-mode not read")
+	}
+	if out, _ := dc.ScriptOutput("ldap-rootdse"); !strings.Contains(out, "namingContexts") {
+		t.Errorf("ldap-rootdse output %q", out)
+	}
+	if dc.Started.IsZero() || !dc.Finished.After(dc.Started) {
+		t.Errorf("scan times %v to %v", dc.Started, dc.Finished)
+	}
+}
+
+func TestHostReasonUntypedPort(t *testing.T) {
+	hosts, err := ParseNmapReader(strings.NewReader("Nmap scan report for 10.0.0.20\n" +
+		"PORT    STATE SERVICE      REASON\n" +
+		"445/tcp open  microsoft-ds syn-ack ttl 127\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hosts["10.0.0.20"]
+	for _, p := range []Port{{445, "tcp"}, {445, ""}} {
+		if got := h.Reason(p); got != "syn-ack ttl 127" {
+			t.Errorf("Reason(%#v) = %q", p, got)
+		}
+	}
+	if got := h.Reason(Port{445, "udp"}); got != "" {
+		t.Errorf("Reason(445/udp) = %q, want none", got)
+	}
+}
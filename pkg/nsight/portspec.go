@@ -0,0 +1,94 @@
+package nsight
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortSpec is a list of ports written compactly as "80", "50001-50050",
+// "161/udp" or a comma-separated mix of those. Ranges are expanded when the
+// spec is parsed, so matching sees plain ports.
+type PortSpec []Port
+
+// ParsePortSpec expands a spec such as "80,443,50001-50050,161/udp". The
+// protocol is case-insensitive and must be tcp or udp; it is returned in
+// lower case, and left empty (meaning TCP) when the item has none.
+func ParsePortSpec(spec string) (PortSpec, error) {
+	var out PortSpec
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		nums, proto, hasProto := strings.Cut(item, "/")
+		if proto = strings.ToLower(proto); hasProto && proto != "tcp" && proto != "udp" {
+			return nil, fmt.Errorf("port %q has unknown protocol %q, want tcp or udp", item, proto)
+		}
+		lo, hi, isRange := strings.Cut(nums, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q in %q", item, spec)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("invalid port range %q in %q", item, spec)
+			}
+		}
+		if last < first {
+			return nil, fmt.Errorf("port range %q is reversed", item)
+		}
+		if first < 1 || last > 65535 {
+			return nil, fmt.Errorf("port %q out of range 1-65535", item)
+		}
+		for n := first; n <= last; n++ {
+			out = append(out, Port{Number: n, Proto: proto})
+		}
+	}
+	return out, nil
+}
+
+// MustParsePortSpec is like ParsePortSpec but panics on a malformed spec.
+// It is meant for built-in signature definitions.
+func MustParsePortSpec(spec string) PortSpec {
+	ports, err := ParsePortSpec(spec)
+	if err != nil {
+		panic(err)
+	}
+	return ports
+}
+
+// UnmarshalJSON accepts a spec string ("80,50001-50050") or an array whose
+// elements are port numbers or spec strings.
+func (s *PortSpec) UnmarshalJSON(b []byte) error {
+	var spec string
+	if err := json.Unmarshal(b, &spec); err == nil {
+		ports, err := ParsePortSpec(spec)
+		*s = ports
+		return err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return fmt.Errorf("ports must be a spec string or an array, got %s", b)
+	}
+	out := PortSpec{}
+	for _, item := range items {
+		var n int
+		if err := json.Unmarshal(item, &n); err == nil {
+			out = append(out, Port{Number: n, Proto: "tcp"})
+			continue
+		}
+		if err := json.Unmarshal(item, &spec); err != nil {
+			return fmt.Errorf("port must be a number or spec string, got %s", item)
+		}
+		ports, err := ParsePortSpec(spec)
+		if err != nil {
+			return err
+		}
+		out = append(out, ports...)
+	}
+	*s = out
+	return nil
+}
@@ -0,0 +1,42 @@
+package nsight
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Port
+		wantErr bool
+	}{
+		{spec: "22", want: []Port{{22, ""}}},
+		{spec: "22,80-82,161/udp", want: []Port{{22, ""}, {80, ""}, {81, ""}, {82, ""}, {161, "udp"}}},
+		{spec: " 443 , 8443/tcp ", want: []Port{{443, ""}, {8443, "tcp"}}},
+		{spec: "500-501/udp", want: []Port{{500, "udp"}, {501, "udp"}}},
+		{spec: "6379/TCP,161/Udp", want: []Port{{6379, "tcp"}, {161, "udp"}}},
+		{spec: "80,,443,", want: []Port{{80, ""}, {443, ""}}},
+		{spec: "65535", want: []Port{{65535, ""}}},
+		{spec: "", want: nil},
+		{spec: "82-80", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "65536", wantErr: true},
+		{spec: "65530-65536", wantErr: true},
+		{spec: "http", wantErr: true},
+		{spec: "80-", wantErr: true},
+		{spec: "-80", wantErr: true},
+		{spec: "132/sctp", wantErr: true},
+		{spec: "22/", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePortSpec(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePortSpec(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual([]Port(got), tt.want) {
+			t.Errorf("ParsePortSpec(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
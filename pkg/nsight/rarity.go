@@ -0,0 +1,54 @@
+package nsight
+
+import "math"
+
+// WeightByRarity returns sigs with every declared port weighted by how rare
+// it is across sigs, so a match resting on a distinctive port such as 9389
+// (AD Web Services) scores higher than one resting on 443. A port declared
+// by the most signatures weighs 1 and each halving of that count adds 1.
+// Weights a signature sets itself are kept. sigs is not modified.
+func WeightByRarity(sigs []Signature) []Signature {
+	counts := make(map[Port]int)
+	most := 0
+	for _, sig := range sigs {
+		for p := range declaredPorts(sig) {
+			counts[p]++
+			most = max(most, counts[p])
+		}
+	}
+	out := make([]Signature, len(sigs))
+	for i, sig := range sigs {
+		weights := make(map[Port]int, len(sig.Weights))
+		for p, w := range sig.Weights {
+			weights[p] = w
+		}
+		for p := range declaredPorts(sig) {
+			if sig.hasWeight(p) {
+				continue
+			}
+			weights[p] = 1 + int(math.Round(math.Log2(float64(most)/float64(counts[p]))))
+		}
+		sig.Weights = weights
+		out[i] = sig
+	}
+	return out
+}
+
+// declaredPorts returns the required, optional and AnyOf ports of sig.
+func declaredPorts(sig Signature) PortSet {
+	ports := NewPortSet(append(append([]Port{}, sig.Required...), sig.Optional...))
+	for _, g := range sig.AnyOf {
+		for _, p := range g.Ports {
+			ports.Add(p)
+		}
+	}
+	return ports
+}
+
+func (sig Signature) hasWeight(p Port) bool {
+	if _, ok := sig.Weights[p.key()]; ok {
+		return true
+	}
+	_, ok := sig.Weights[Port{Number: p.Number}]
+	return ok && p.key().Proto == "tcp"
+}
@@ -0,0 +1,261 @@
+package nsight
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaJSON is the JSON Schema for signature files. Editors can use it for
+// completion; Lint checks files against it.
+//
+//go:embed schema.json
+var schemaJSON []byte
+
+// SignatureSchema returns the JSON Schema describing signature files, in
+// either form LoadSignatures reads.
+func SignatureSchema() []byte {
+	return append([]byte(nil), schemaJSON...)
+}
+
+// schema is the part of JSON Schema that schema.json uses.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Enum                 []string           `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	OneOf                []*schema          `json:"oneOf"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            int                `json:"minLength"`
+	Pattern              string             `json:"pattern"`
+	Defs                 map[string]*schema `json:"$defs"`
+}
+
+var signatureSchema = func() *schema {
+	var s schema
+	if err := json.Unmarshal(schemaJSON, &s); err != nil {
+		panic("nsight: bad embedded schema: " + err.Error())
+	}
+	return &s
+}()
+
+// schemaFindings is what checking a value against the schema turned up:
+// problems that make it invalid, and fields the schema doesn't know, which
+// loading ignores.
+type schemaFindings struct {
+	problems, ignored []string
+}
+
+// schemaProblems checks one decoded signature against the schema. Like the
+// decoder, it matches field names and severity names ignoring case.
+func schemaProblems(sig any) schemaFindings {
+	var out schemaFindings
+	signatureSchema.check(signatureSchema.Defs["signature"], sig, "", &out)
+	return out
+}
+
+func (root *schema) check(s *schema, v any, path string, out *schemaFindings) {
+	s = root.resolve(s)
+	at := func(format string, args ...any) string {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		return msg
+	}
+	report := func(format string, args ...any) {
+		out.problems = append(out.problems, at(format, args...))
+	}
+	if len(s.OneOf) > 0 {
+		// Report against the one alternative of the value's type, if there
+		// is one, rather than against all of them.
+		var fits []*schema
+		for _, alt := range s.OneOf {
+			var errs schemaFindings
+			root.check(alt, v, path, &errs)
+			if len(errs.problems) == 0 {
+				out.ignored = append(out.ignored, errs.ignored...)
+				return
+			}
+			if root.resolve(alt).Type == jsonType(v) {
+				fits = append(fits, alt)
+			}
+		}
+		if len(fits) == 1 {
+			root.check(fits[0], v, path, out)
+			return
+		}
+		report("must be %s", root.describe(s))
+		return
+	}
+	if s.Type != "" && s.Type != jsonType(v) && !(s.Type == "number" && jsonType(v) == "integer") {
+		report("must be %s", article(s.Type))
+		return
+	}
+	if len(s.Enum) > 0 {
+		str, _ := v.(string)
+		ok := false
+		for _, e := range s.Enum {
+			ok = ok || strings.EqualFold(str, e)
+		}
+		if !ok {
+			report("%s is not one of %s", compact(v), strings.Join(s.Enum, ", "))
+		}
+		return
+	}
+	switch v := v.(type) {
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			report("%s is below the minimum of %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			report("%s is above the maximum of %v", v, *s.Maximum)
+		}
+	case string:
+		if len(v) < s.MinLength {
+			report("must not be empty")
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(v) {
+			report("%q is not %s", v, root.describe(s))
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				root.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i), out)
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, want := range s.Required {
+			if _, ok := lookupFold(v, want); !ok {
+				report("missing %q", want)
+			}
+		}
+		for _, k := range keys {
+			sub := fieldPath(path, k)
+			if name, ok := propertyFold(s.Properties, k); ok {
+				root.check(s.Properties[name], v[k], sub, out)
+				continue
+			}
+			switch extra := s.AdditionalProperties; {
+			case string(extra) == "false":
+				out.ignored = append(out.ignored, at("unknown field %q", k))
+			case len(extra) > 0 && string(extra) != "true":
+				var each schema
+				if err := json.Unmarshal(extra, &each); err == nil {
+					root.check(&each, v[k], sub, out)
+				}
+			}
+		}
+	}
+}
+
+func (root *schema) resolve(s *schema) *schema {
+	if s.Ref != "" {
+		return root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+	}
+	return s
+}
+
+// describe names what s accepts, for error messages.
+func (root *schema) describe(s *schema) string {
+	switch s = root.resolve(s); s {
+	case root.Defs["portSpecString"]:
+		return `a port spec such as "80,443", "161/udp" or "50001-50050"`
+	case root.Defs["portSpec"]:
+		return root.describe(root.Defs["portSpecString"]) + ", or an array of ports"
+	}
+	var kinds []string
+	for _, alt := range s.OneOf {
+		kinds = append(kinds, root.describe(alt))
+	}
+	if len(kinds) > 0 {
+		return strings.Join(kinds, " or ")
+	}
+	return article(s.Type)
+}
+
+// jsonType returns the JSON Schema type of a value decoded with UseNumber.
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func article(typ string) string {
+	switch typ {
+	case "integer", "array", "object":
+		return "an " + typ
+	case "":
+		return "a value"
+	}
+	return "a " + typ
+}
+
+func compact(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// fieldPath extends a field path such as anyOf[0] with key k.
+func fieldPath(path, k string) string {
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
+
+// lookupFold finds key in m ignoring case, as encoding/json does.
+func lookupFold(m map[string]any, key string) (any, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// asObject returns v as a JSON object, or nil if it is something else.
+func asObject(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func propertyFold(props map[string]*schema, key string) (string, bool) {
+	if _, ok := props[key]; ok {
+		return key, true
+	}
+	for name := range props {
+		if strings.EqualFold(name, key) {
+			return name, true
+		}
+	}
+	return "", false
+}
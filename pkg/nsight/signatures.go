@@ -0,0 +1,56 @@
+package nsight
+
+// KnownSignatures returns the built-in signature set.
+func KnownSignatures() []Signature {
+	return []Signature{
+		{Name: "SMB / NetBIOS file share", Category: "Windows", Severity: Medium, Required: TCP(139, 445), Notes: "Check for null sessions, guest shares and whether SMB signing is required."},
+		{Name: "Active Directory Domain Controller", Category: "Windows", Severity: High, Required: TCP(53, 88, 389, 445, 464), Optional: TCP(636, 3268, 3269, 5985, 9389), Weights: map[Port]int{{Number: 88}: 3, {Number: 464}: 2, {Number: 3268}: 2, {Number: 9389}: 2}, Notes: "Check for anonymous LDAP binds, AS-REP roastable and Kerberoastable accounts.", Supersedes: []string{"SMB / NetBIOS file share"}},
+		{Name: "Windows RPC services (EPM + dynamic RPC)", Category: "Windows", Severity: Low, Required: TCP(135)},
+		{Name: "Windows Remote Management / WinRM", Category: "Windows", Severity: Medium, Required: TCP(5985), Optional: TCP(5986)},
+		{Name: "NFS server (rpcbind + nfsd)", Category: "File sharing", Severity: Medium, Required: TCP(111, 2049), Optional: TCP(20048, 4045, 4049), Notes: "List exports with showmount -e; world-readable or no_root_squash exports are common."},
+		{Name: "FTP", Category: "File sharing", Severity: Low, Required: TCP(21), Optional: TCP(20), Notes: "Try anonymous login; credentials cross the wire in clear text."},
+		{Name: "Mail stack (SMTP + POP/IMAP)", Category: "Mail", Severity: Info, Required: TCP(25), AnyOf: []PortGroup{{Name: "mail access", Ports: TCP(110, 143, 993, 995)}}},
+		{Name: "SIP / VoIP server", Category: "VoIP", Severity: Low, Required: TCP(5060)},
+		{Name: "Network printer (JetDirect + LPD)", Category: "Printing", Severity: Low, Required: TCP(515, 9100)},
+		{Name: "Oracle Database", Category: "Databases", Severity: Medium, Required: TCP(1521), Optional: TCP(1522, 2483, 2484)},
+		{Name: "MySQL / MariaDB", Category: "Databases", Severity: Medium, Required: TCP(3306), Optional: TCP(33060)},
+		{Name: "Microsoft SQL Server", Category: "Databases", Severity: Medium, Required: TCP(1433)},
+		{Name: "PostgreSQL", Category: "Databases", Severity: Medium, Required: TCP(5432), Optional: TCP(5433)},
+		{Name: "IBM Db2 Database", Category: "Databases", Severity: Medium, Required: TCP(50000), Optional: MustParsePortSpec("50001-50050")},
+		{Name: "SAP NetWeaver Application Server", Category: "Enterprise applications", Severity: High, Required: TCP(3200, 3300), Optional: TCP(3600, 8000, 8001, 3299)},
+		{Name: "Elasticsearch", Category: "Databases", Severity: High, Required: TCP(9200), Optional: TCP(9300), Notes: "Older releases have no authentication by default; try GET /_cat/indices."},
+		{Name: "Splunk Enterprise", Category: "Enterprise applications", Severity: Medium, Required: TCP(8000, 8089, 9997), Optional: append(TCP(8088), UDP(514)...)},
+		{Name: "Web server (HTTP + HTTPS)", Category: "Web", Severity: Info, Required: TCP(80, 443), Optional: TCP(8080, 8443)},
+		{Name: "Web application with dev server", Category: "Web", Severity: Low, Required: TCP(80, 443), AnyOf: []PortGroup{{Name: "dev server", Ports: TCP(3000, 4200, 5000, 5173, 8000, 8080)}}, Supersedes: []string{"Web server (HTTP + HTTPS)"}},
+		{Name: "Node.js dev stack (Express)", Category: "Web", Severity: Low, Required: TCP(3000), BannerContains: map[Port]string{{Number: 3000}: "express"}},
+		{Name: "Apache Tomcat", Category: "Web", Severity: Medium, Required: TCP(8080), Optional: TCP(8005, 8009, 8443), BannerContains: map[Port]string{{Number: 8080}: "tomcat"}},
+		{Name: "F5 BIG-IP", Category: "Load balancers", Severity: High, Required: TCP(443, 8443), Optional: TCP(22, 80, 4353), Weights: map[Port]int{{Number: 4353}: 3}, Notes: "8443 is the configuration utility on single-NIC deployments and 4353 (iQuery) is BIG-IP's own; check /tmui and iControl REST for CVE-2020-5902 and CVE-2022-1388.", Supersedes: []string{"Web server (HTTP + HTTPS)", "Reverse proxy / load balancer", "VMware vCenter Server"}},
+		{Name: "Citrix NetScaler ADC", Category: "Load balancers", Severity: High, Required: TCP(443), AnyOf: []PortGroup{{Name: "HA/RPC", Ports: TCP(3008, 3009, 3010)}}, Optional: TCP(22, 80), Weights: map[Port]int{{Number: 3008}: 2, {Number: 3009}: 2, {Number: 3010}: 2}, Notes: "3008-3010 carry management RPC and HA sync and should not be reachable from outside; check the Gateway for CVE-2019-19781 and CVE-2023-3519.", Supersedes: []string{"Web server (HTTP + HTTPS)", "Reverse proxy / load balancer", "VMware vCenter Server"}},
+		{Name: "Reverse proxy / load balancer", Category: "Load balancers", Severity: Low, Required: TCP(80, 443), AnyOf: []PortGroup{{Name: "admin", Ports: TCP(8443, 9443, 10443)}}, Notes: "A second HTTPS port beside 80/443 is often the appliance's admin interface; browse to it and look for a vendor login page.", Supersedes: []string{"Web server (HTTP + HTTPS)"}},
+		{Name: "Kubernetes control plane", Category: "Containers", Severity: High, Required: TCP(6443, 10250), Optional: TCP(2379, 2380, 10257, 10259), Weights: map[Port]int{{Number: 6443}: 2}, Notes: "Check the API server and kubelet for anonymous access."},
+		{Name: "Kubernetes worker node", Category: "Containers", Severity: Medium, Required: TCP(10250), Optional: TCP(10255, 10256), Forbidden: TCP(6443), Notes: "The read-only kubelet port 10255 needs no authentication."},
+		{Name: "etcd", Category: "Containers", Severity: Critical, Required: TCP(2379, 2380), Forbidden: TCP(6443), Notes: "An etcd reachable without client certificates exposes every cluster secret."},
+		{Name: "Docker Engine API", Category: "Containers", Severity: Critical, AnyOf: []PortGroup{{Name: "Docker API", Ports: TCP(2375, 2376)}}, Notes: "2375 is the unauthenticated plain-text API and amounts to root on the host."},
+		{Name: "VMware vCenter Server", Category: "Virtualisation", Severity: High, Required: TCP(443), Optional: TCP(5480, 902)},
+		{Name: "MongoDB Database", Category: "Databases", Severity: High, Required: TCP(27017), Optional: TCP(27018, 27019), Notes: "Check whether the instance accepts unauthenticated connections."},
+		{Name: "Redis", Category: "Databases", Severity: High, Required: TCP(6379), Optional: TCP(26379, 16379), Notes: "Redis often runs unauthenticated on 6379; try INFO with redis-cli."},
+		{Name: "Apache Cassandra", Category: "Databases", Severity: Medium, Required: TCP(9042), Optional: TCP(7000, 9160)},
+		{Name: "Jenkins", Category: "CI/CD", Severity: High, Required: TCP(8080, 50000), Weights: map[Port]int{{Number: 50000}: 2}, Notes: "Check /script and /asynchPeople for anonymous access; 50000 is the inbound agent port.", Supersedes: []string{"IBM Db2 Database"}},
+		{Name: "GitLab", Category: "CI/CD", Severity: Medium, Required: TCP(443, 5050), Optional: TCP(22, 80), Weights: map[Port]int{{Number: 5050}: 2}, Notes: "5050 is the container registry; check /explore for public projects.", Supersedes: []string{"Web server (HTTP + HTTPS)"}},
+		{Name: "JFrog Artifactory", Category: "CI/CD", Severity: Medium, Required: TCP(8081, 8082), Optional: TCP(8046, 8070), Notes: "Check for anonymous read access to repositories."},
+		{Name: "Sonatype Nexus Repository", Category: "CI/CD", Severity: Medium, Required: TCP(8081), Optional: TCP(8082, 8083), BannerContains: map[Port]string{{Number: 8081}: "nexus"}, Notes: "Older releases ship with admin/admin123.", Supersedes: []string{"JFrog Artifactory"}},
+		{Name: "SonarQube", Category: "CI/CD", Severity: Medium, Required: TCP(9000), BannerContains: map[Port]string{{Number: 9000}: "sonar"}, Notes: "Try admin/admin; source code and secrets often leak through public projects."},
+		{Name: "Windows remote-admin surface", Category: "Remote access", Severity: High, AnyOf: []PortGroup{{Name: "remote admin", Ports: TCP(135, 445, 3389, 5985, 5986), Min: 2}}, Weights: map[Port]int{{Number: 3389}: 2, {Number: 5985}: 2}, Notes: "Valid credentials likely give a shell through more than one of these; try each with the same account.", Supersedes: []string{"Windows Remote Management / WinRM", "Windows RPC services (EPM + dynamic RPC)"}},
+		{Name: "Unix remote-admin surface", Category: "Remote access", Severity: Medium, AnyOf: []PortGroup{{Name: "remote admin", Ports: TCP(22, 23, 512, 513, 514, 5900), Min: 2}}},
+		{Name: "Cleartext remote login (Telnet / r-services)", Category: "Remote access", Severity: High, AnyOf: []PortGroup{{Name: "cleartext login", Ports: TCP(23, 512, 513, 514)}}, Notes: "Credentials cross the wire in clear text, and rsh/rlogin may trust hosts by address alone."},
+		{Name: "Honeypot (excessive open ports)", Category: "Anomalies", Severity: Info, MinOpenPorts: 100, Notes: "Real servers rarely listen on this many ports; a honeypot, tarpit or firewall answering for every port is likelier, so treat other matches on this host with suspicion."},
+		{Name: "Suspicious/backdoor listener", Category: "Anomalies", Severity: High, AnyOf: []PortGroup{{Name: "backdoor", Ports: TCP(1337, 4444, 5555, 12345, 31337)}}, Notes: "Common defaults for Metasploit handlers (4444), NetBus (12345), Back Orifice (31337) and adb over TCP (5555); check what is listening before assuming a compromise."},
+		{Name: "Modbus/TCP", Category: "ICS/SCADA", Severity: High, Required: TCP(502), Notes: "Modbus has no authentication; any client can read and write coils and registers."},
+		{Name: "DNP3 outstation", Category: "ICS/SCADA", Severity: High, AnyOf: []PortGroup{{Name: "DNP3", Ports: append(TCP(20000), UDP(20000)...)}}, Notes: "Check whether Secure Authentication is enabled."},
+		{Name: "EtherNet/IP (CIP)", Category: "ICS/SCADA", Severity: High, AnyOf: []PortGroup{{Name: "EtherNet/IP", Ports: append(TCP(44818), UDP(44818)...)}}, Optional: UDP(2222), Notes: "The enip-info NSE script lists the device vendor and firmware."},
+		{Name: "Siemens S7 PLC (S7comm)", Category: "ICS/SCADA", Severity: High, Required: TCP(102), Notes: "ISO-TSAP on 102 is also used by some Exchange and X.400 systems; s7-info confirms a PLC."},
+		{Name: "BACnet building controller", Category: "ICS/SCADA", Severity: High, Required: UDP(47808), Notes: "BACnet/IP is unauthenticated; found with a UDP scan (-sU)."},
+		{Name: "SNMP agent", Category: "Network management plane", Severity: Medium, Required: UDP(161), Optional: UDP(162), Notes: "Try the public and private communities with snmpwalk or onesixtyone; v1 and v2c send them in clear text."},
+		{Name: "Network management plane (SNMP + syslog/NetFlow)", Category: "Network management plane", Severity: High, Required: UDP(161), AnyOf: []PortGroup{{Name: "telemetry", Ports: UDP(162, 514, 2055)}}, Optional: TCP(22, 23), Notes: "Likely a router, switch or collector; management traffic is often reachable from networks that should never see it.", Supersedes: []string{"SNMP agent"}},
+	}
+}
@@ -0,0 +1,28 @@
+package nsight
+
+import "testing"
+
+func TestNetworkManagementSignatures(t *testing.T) {
+	sigs := Signatures()
+	tests := []struct {
+		name  string
+		ports []Port
+		want  string // "" for neither signature
+		not   string
+	}{
+		{"SNMP only", UDP(161), "SNMP agent", "Network management plane (SNMP + syslog/NetFlow)"},
+		{"SNMP and syslog", UDP(161, 514), "Network management plane (SNMP + syslog/NetFlow)", "SNMP agent"},
+		{"161 over TCP", TCP(161), "", "SNMP agent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(DropSuperseded(Match(hostWith(tt.ports...), sigs), sigs))
+			if tt.want != "" && !contains(got, tt.want) {
+				t.Errorf("want %q among %v", tt.want, got)
+			}
+			if contains(got, tt.not) {
+				t.Errorf("did not want %q among %v", tt.not, got)
+			}
+		})
+	}
+}
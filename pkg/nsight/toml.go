@@ -0,0 +1,386 @@
+package nsight
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tomlToJSON converts a TOML signature file to the equivalent JSON object, so
+// both formats share one decoder and one schema. It reads the part of TOML a
+// signature file needs: tables, arrays of tables, dotted and quoted keys,
+// single-line strings, integers (decimal, 0x, 0o and 0b), floats, booleans,
+// arrays and inline tables. Anything else, such as multi-line strings and
+// dates, is an error rather than being misread.
+func tomlToJSON(data string) ([]byte, error) {
+	p := &tomlParser{s: data, root: map[string]any{}}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(p.root)
+}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	root map[string]any
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.s[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool { return p.pos >= len(p.s) }
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// skipSpace skips blanks on the current line.
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipBlank skips blanks, comments and newlines.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endLine consumes the rest of a line, which may hold only a comment.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if !p.eof() && p.peek() != '\n' {
+		return p.errorf("unexpected %q after value", p.peek())
+	}
+	return nil
+}
+
+func (p *tomlParser) parse() error {
+	current := p.root
+	for p.skipBlank(); !p.eof(); p.skipBlank() {
+		if p.peek() != '[' {
+			if err := p.keyValue(current); err != nil {
+				return err
+			}
+			if err := p.endLine(); err != nil {
+				return err
+			}
+			continue
+		}
+		p.pos++
+		array := p.peek() == '['
+		if array {
+			p.pos++
+		}
+		p.skipSpace()
+		path, err := p.key()
+		if err != nil {
+			return err
+		}
+		closing := "]"
+		if array {
+			closing = "]]"
+		}
+		p.skipSpace()
+		if !strings.HasPrefix(p.s[p.pos:], closing) {
+			return p.errorf("table header needs a closing %s", closing)
+		}
+		p.pos += len(closing)
+		if current, err = p.table(path, array); err != nil {
+			return err
+		}
+		if err := p.endLine(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// table finds or creates the table a [path] or [[path]] header names. An
+// array of tables gets a new element each time its header appears.
+func (p *tomlParser) table(path []string, array bool) (map[string]any, error) {
+	last := len(path) - 1
+	if array {
+		last = len(path) - 2
+	}
+	t := p.root
+	for _, k := range path[:last+1] {
+		switch v := t[k].(type) {
+		case nil:
+			next := map[string]any{}
+			t[k] = next
+			t = next
+		case map[string]any:
+			t = v
+		case []any:
+			if len(v) == 0 {
+				return nil, p.errorf("%s is an empty array, not a table", k)
+			}
+			elem, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, p.errorf("%s is not a table", k)
+			}
+			t = elem
+		default:
+			return nil, p.errorf("%s is not a table", k)
+		}
+	}
+	if !array {
+		return t, nil
+	}
+	k := path[len(path)-1]
+	elems, ok := t[k].([]any)
+	if t[k] != nil && !ok {
+		return nil, p.errorf("%s is not an array of tables", k)
+	}
+	elem := map[string]any{}
+	t[k] = append(elems, elem)
+	return elem, nil
+}
+
+// keyValue parses "key = value" into t.
+func (p *tomlParser) keyValue(t map[string]any) error {
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() != '=' {
+		return p.errorf("expected = after key %s", strings.Join(path, "."))
+	}
+	p.pos++
+	p.skipSpace()
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	for _, k := range path[:len(path)-1] {
+		next, ok := t[k].(map[string]any)
+		if t[k] != nil && !ok {
+			return p.errorf("%s is not a table", k)
+		}
+		if next == nil {
+			next = map[string]any{}
+			t[k] = next
+		}
+		t = next
+	}
+	k := path[len(path)-1]
+	if _, dup := t[k]; dup {
+		return p.errorf("key %s is defined twice", k)
+	}
+	t[k] = v
+	return nil
+}
+
+// key parses a possibly dotted key such as weights."161/udp".
+func (p *tomlParser) key() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpace()
+		var k string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKey(p.peek()) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a key, found %q", c)
+			}
+			k = p.s[start:p.pos]
+		}
+		path = append(path, k)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (any, error) {
+	switch c := p.peek(); c {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	word := p.s[start:p.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, p.errorf("missing value")
+	}
+	return p.number(word)
+}
+
+// TOML's number syntax: decimal integers have no leading zeros, prefixed
+// integers carry no sign, and an underscore must sit between two digits.
+var (
+	tomlDecimal  = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlPrefixed = map[string]*regexp.Regexp{
+		"0x": regexp.MustCompile(`^[0-9A-Fa-f](_?[0-9A-Fa-f])*$`),
+		"0o": regexp.MustCompile(`^[0-7](_?[0-7])*$`),
+		"0b": regexp.MustCompile(`^[01](_?[01])*$`),
+	}
+	tomlFloat = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+)
+
+// number parses an integer or float written as TOML allows, rejecting what
+// strconv would read differently, such as 0502 as octal.
+func (p *tomlParser) number(word string) (any, error) {
+	digits := strings.ReplaceAll(word, "_", "")
+	if tomlDecimal.MatchString(word) {
+		if n, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			return n, nil
+		}
+		return nil, p.errorf("integer %s out of range", word)
+	}
+	if len(word) > 2 {
+		if re, ok := tomlPrefixed[word[:2]]; ok && re.MatchString(word[2:]) {
+			base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[word[1]]
+			if n, err := strconv.ParseInt(digits[2:], base, 64); err == nil {
+				return n, nil
+			}
+			return nil, p.errorf("integer %s out of range", word)
+		}
+	}
+	switch strings.TrimLeft(word, "+-") {
+	case "inf", "nan":
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f, nil
+		}
+	}
+	if tomlFloat.MatchString(word) {
+		if f, err := strconv.ParseFloat(digits, 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, p.errorf("unsupported value %q", word)
+}
+
+// str parses a basic ("...") or literal ('...') single-line string.
+func (p *tomlParser) str() (string, error) {
+	quote := p.peek()
+	if strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(quote), 3)) {
+		return "", p.errorf("multi-line strings are not supported")
+	}
+	start := p.pos
+	for p.pos++; !p.eof() && p.peek() != quote && p.peek() != '\n'; p.pos++ {
+		if quote == '"' && p.peek() == '\\' {
+			p.pos++
+		}
+	}
+	if p.peek() != quote {
+		return "", p.errorf("unterminated string")
+	}
+	p.pos++
+	raw := p.s[start:p.pos]
+	if quote == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	s, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", p.errorf("invalid string %s", raw)
+	}
+	return s, nil
+}
+
+// array parses [a, b, ...], which may span lines and end with a comma.
+func (p *tomlParser) array() ([]any, error) {
+	out := []any{}
+	p.pos++
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return out, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable parses {key = value, ...} on one line.
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	t := map[string]any{}
+	p.pos++
+	for first := true; ; first = false {
+		p.skipSpace()
+		if p.peek() == '}' && first {
+			p.pos++
+			return t, nil
+		}
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return t, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package nsight
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTOMLRejects(t *testing.T) {
+	tests := []struct {
+		name, doc, err string
+	}{
+		{"table under an empty array", "signatures = []\n[signatures.x]\nname = \"a\"\n", "empty array"},
+		{"table under an array of values", "signatures = [1]\n[signatures.x]\n", "not a table"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tomlToJSON(tt.doc)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("want an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestTOMLNumbers(t *testing.T) {
+	tests := []struct {
+		word string
+		want any // nil for an error
+	}{
+		{"502", int64(502)},
+		{"0", int64(0)},
+		{"-7", int64(-7)},
+		{"+7", int64(7)},
+		{"65_535", int64(65535)},
+		{"0x1F6", int64(502)},
+		{"0o766", int64(502)},
+		{"0b111110110", int64(502)},
+		{"0.5", 0.5},
+		{"1e3", 1000.0},
+		{"1_000.5", 1000.5},
+		{"inf", math.Inf(1)},
+		{"-inf", math.Inf(-1)},
+		{"0502", nil},
+		{"00", nil},
+		{"1__0", nil},
+		{"_10", nil},
+		{"10_", nil},
+		{"-0x10", nil},
+		{"0x", nil},
+		{"0o8", nil},
+		{"01.5", nil},
+		{"2026-10-13", nil},
+		{"99999999999999999999", nil},
+	}
+	for _, tt := range tests {
+		got, err := (&tomlParser{}).number(tt.word)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s: want an error, got %v", tt.word, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %v (%v), want %v", tt.word, got, err, tt.want)
+		}
+	}
+	sigs, err := tomlToJSON("[[signatures]]\nname = \"Modbus\"\nrequired = [0502]\n")
+	if err == nil {
+		t.Errorf("want 0502 rejected, got %s", sigs)
+	}
+}
+
+func TestLintTOMLTableUnderEmptyArray(t *testing.T) {
+	path := writeFile(t, "sigs.toml", "signatures = []\n[signatures.x]\nname = \"a\"\n")
+	if _, _, _, err := Lint(path); err == nil {
+		t.Fatal("want an error")
+	}
+}
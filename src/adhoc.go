@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// portLists holds the hosts --ports describes, by the name they are reported
+// under. readScan returns them in place of parsing a file.
+var portLists = map[string]*nsight.Host{}
+
+// addPortList builds a host with the ports in spec open, such as
+// "22,80,443,161/udp", and returns the name to pass on as an input path.
+func addPortList(spec string) (string, error) {
+	ports, err := nsight.ParsePortSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	if len(ports) == 0 {
+		return "", fmt.Errorf("no ports in %q", spec)
+	}
+	h := nsight.NewHost()
+	for _, p := range ports {
+		h.Add(p, "")
+	}
+	name := "--ports " + spec
+	portLists[name] = h
+	return name, nil
+}
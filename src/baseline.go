@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// baseline holds the --baseline entries: ports and signatures expected on
+// given hosts, which are left out of the report so only drift from the
+// baseline shows.
+type baseline []baselineEntry
+
+// baselineEntry approves ports, or else the signature sig, on the hosts in
+// hosts; a nil hosts means every host.
+type baselineEntry struct {
+	hosts hostFilter
+	ports nsight.PortSet
+	sig   string
+	line  int // in the baseline file, for checkSignatures
+}
+
+// loadBaseline reads a baseline file: one host:port or host:signature entry
+// per line, such as "10.0.0.5:445", "10.0.0.0/24:161/udp" or
+// "10.0.0.9:PostgreSQL". The host is an address, a CIDR range or * for any
+// host; an IPv6 address goes in brackets, "[fe80::1]:22". The port part
+// takes any port spec. Blank lines and lines starting with # are skipped.
+func loadBaseline(path string) (baseline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var b baseline
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := parseBaselineEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		e.line = n
+		b = append(b, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func parseBaselineEntry(line string) (baselineEntry, error) {
+	host, rest, ok := strings.Cut(line, ":")
+	if strings.HasPrefix(line, "[") {
+		addr, after, closed := strings.Cut(line[1:], "]")
+		host = addr
+		rest, ok = strings.CutPrefix(after, ":")
+		ok = ok && closed
+	}
+	host, rest = strings.TrimSpace(host), strings.TrimSpace(rest)
+	if !ok || host == "" || rest == "" {
+		return baselineEntry{}, fmt.Errorf("%q is not host:port or host:signature", line)
+	}
+	var e baselineEntry
+	if host != "*" {
+		if err := e.hosts.Set(host); err != nil {
+			return baselineEntry{}, err
+		}
+	}
+	if ports, err := nsight.ParsePortSpec(rest); err == nil {
+		e.ports = nsight.NewPortSet(ports)
+	} else {
+		e.sig = rest
+	}
+	return e, nil
+}
+
+// checkSignatures makes sure every signature the baseline at path names is
+// one of sigs, ignoring case, so a typo such as "Postgres" or "544x" is an
+// error rather than an entry that silently approves nothing.
+func (b baseline) checkSignatures(path string, sigs []nsight.Signature) error {
+	known := make(map[string]bool, len(sigs))
+	for _, sig := range sigs {
+		known[strings.ToLower(sig.Name)] = true
+	}
+	for _, e := range b {
+		if e.sig != "" && !known[strings.ToLower(e.sig)] {
+			return fmt.Errorf("%s:%d: unknown signature %q", path, e.line, e.sig)
+		}
+	}
+	return nil
+}
+
+// approves reports whether the baseline expects port p on host.
+func (b baseline) approves(host string, p nsight.Port) bool {
+	for _, e := range b {
+		if e.ports.Has(p) && e.hosts.keeps(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches drops the matches on host the baseline expects: those of an
+// approved signature, and those resting only on approved ports.
+func (b baseline) matches(host string, matches []nsight.Result) []nsight.Result {
+	if len(b) == 0 {
+		return matches
+	}
+	kept := matches[:0:0]
+	for _, m := range matches {
+		if !b.expects(host, m) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+func (b baseline) expects(host string, m nsight.Result) bool {
+	for _, e := range b {
+		if e.sig != "" && strings.EqualFold(e.sig, m.Signature) && e.hosts.keeps(host) {
+			return true
+		}
+	}
+	ports := append(append(m.AnyOfPresent(), m.RequiredPresent...), m.OptionalPresent...)
+	for _, p := range ports {
+		if !b.approves(host, p) {
+			return false
+		}
+	}
+	return len(ports) > 0
+}
+
+// ports drops the ports on host the baseline expects, such as from the
+// unexplained list.
+func (b baseline) ports(host string, ports []nsight.Port) []nsight.Port {
+	if len(b) == 0 {
+		return ports
+	}
+	kept := []nsight.Port{}
+	for _, p := range ports {
+		if !b.approves(host, p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBaselineUnknownSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string // empty when the baseline loads
+	}{
+		{"known names", "10.0.0.9:PostgreSQL\n10.0.0.9:redis\n10.0.0.5:445\n", ""},
+		{"misspelt name", "10.0.0.9:PostgreSQL\n\n10.0.0.9:Postgres\n", `:3: unknown signature "Postgres"`},
+		{"bad port", "# ports\n10.0.0.9:544x\n", `:2: unknown signature "544x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "baseline.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			code, out, errOut := runCLI(t, "--quiet", "--baseline", path, "testdata/dc.nmap")
+			if tt.wantErr == "" {
+				if code != exitMatch || strings.Contains(out, "PostgreSQL") || strings.Contains(out, "Redis") {
+					t.Errorf("exit %d, stderr %q, report:\n%s", code, errOut, out)
+				}
+				return
+			}
+			if code != exitError || !strings.Contains(errOut, path+tt.wantErr) {
+				t.Errorf("exit %d, stderr %q; want %q", code, errOut, path+tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// cacheFormat changes whenever cacheEntry does, so old entries are ignored.
+const cacheFormat = 2
+
+// cacheEntry is the parse of one scan file as stored under the cache
+// directory: the hosts before --host and --ignore-port are applied, and the
+// lines --strict skipped.
+type cacheEntry struct {
+	Hosts   map[string]cachedHost `json:"hosts"`
+	Skipped []string              `json:"skipped,omitempty"`
+}
+
+// cachedHost is a Host with its maps keyed by port strings such as
+// "161/udp". Scripts uses "host" for host script results.
+type cachedHost struct {
+	Open     []nsight.Port                `json:"open"`
+	Filtered []nsight.Port                `json:"filtered,omitempty"`
+	Banners  map[string]string            `json:"banners,omitempty"`
+	Reasons  map[string]string            `json:"reasons,omitempty"`
+	Scripts  map[string]map[string]string `json:"scripts,omitempty"`
+	Started  time.Time                    `json:"started"`
+	Finished time.Time                    `json:"finished"`
+}
+
+// parseCached is readScan for regular files by way of the cache: a file
+// whose content was parsed before with the same options is loaded from its
+// entry instead. Lines --strict skipped are added to skipped either way.
+// Anything that goes wrong with the cache itself only costs the speed-up.
+func parseCached(path string, opts nsight.ParseOptions, skipped *[]string) (map[string]*nsight.Host, error) {
+	dir, err := os.UserCacheDir()
+	if !useCache || err != nil || !isRegularFile(path) {
+		return readScan(path, opts)
+	}
+	before, err := hashFile(path)
+	if err != nil {
+		return readScan(path, opts)
+	}
+	entry := filepath.Join(dir, "nsight", cacheName(before, opts))
+	if hosts, lines, ok := loadCache(entry); ok {
+		debugf("%s: loaded from cache %s", path, entry)
+		*skipped = append(*skipped, lines...)
+		return hosts, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Hash what is actually parsed, so a file rewritten in the meantime
+	// (under --watch, say) isn't cached under its old content.
+	after := &fileHash{sum: sha256.New()}
+	r := io.TeeReader(f, after)
+	hosts, err := nsight.ParseNmapReaderWith(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(io.Discard, r); err == nil && after.id() == before.id() {
+		if err := saveCache(entry, hosts, *skipped); err != nil {
+			debugf("cannot write cache entry: %v", err)
+		}
+	}
+	return hosts, nil
+}
+
+// fileHash takes the SHA-256 and size of a file's content as it is written.
+type fileHash struct {
+	sum  hash.Hash
+	size int64
+}
+
+func (h *fileHash) Write(b []byte) (int, error) {
+	h.size += int64(len(b))
+	return h.sum.Write(b)
+}
+
+// id identifies the content written so far.
+func (h *fileHash) id() string {
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(h.sum.Sum(nil)), h.size)
+}
+
+func hashFile(path string) (*fileHash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := &fileHash{sum: sha256.New()}
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// cacheName names the entry for content h parsed with opts.
+func cacheName(h *fileHash, opts nsight.ParseOptions) string {
+	build := version
+	if build == "dev" {
+		// A development build can change the parser without changing its
+		// version, so key its entries to the binary itself.
+		if exe, err := os.Executable(); err == nil {
+			if info, err := os.Stat(exe); err == nil {
+				build += fmt.Sprintf("%x", info.ModTime().UnixNano())
+			}
+		}
+	}
+	name := fmt.Sprintf("%s-v%d-%s", h.id(), cacheFormat, build)
+	if opts.IncludeFiltered {
+		name += "-filtered"
+	}
+	if opts.Strict {
+		name += "-strict"
+	}
+	return name + ".json"
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// loadCache reads the entry at path, reporting false if there is none or it
+// can't be used.
+func loadCache(path string) (map[string]*nsight.Host, []string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		debugf("ignoring cache entry %s: %v", path, err)
+		return nil, nil, false
+	}
+	hosts := make(map[string]*nsight.Host, len(entry.Hosts))
+	for addr, c := range entry.Hosts {
+		h := nsight.NewHost()
+		filtered := nsight.NewPortSet(c.Filtered)
+		for _, p := range c.Open {
+			if filtered.Has(p) {
+				h.AddFiltered(p, c.Banners[p.String()])
+			} else {
+				h.Add(p, c.Banners[p.String()])
+			}
+			h.SetReason(p, c.Reasons[p.String()])
+		}
+		for key, scripts := range c.Scripts {
+			p := nsight.HostScripts
+			if key != "host" {
+				ports, err := nsight.ParsePortSpec(key)
+				if err != nil || len(ports) != 1 {
+					debugf("ignoring cache entry %s: bad port %q", path, key)
+					return nil, nil, false
+				}
+				p = ports[0]
+			}
+			for id, out := range scripts {
+				h.AddScript(p, id, out)
+			}
+		}
+		h.Started, h.Finished = c.Started, c.Finished
+		hosts[addr] = h
+	}
+	return hosts, entry.Skipped, true
+}
+
+// saveCache writes hosts and skipped to path. The entry is written to a
+// temporary file first so a concurrent run never reads half of one.
+func saveCache(path string, hosts map[string]*nsight.Host, skipped []string) error {
+	entry := cacheEntry{Hosts: make(map[string]cachedHost, len(hosts)), Skipped: skipped}
+	for addr, h := range hosts {
+		c := cachedHost{
+			Banners:  make(map[string]string),
+			Reasons:  make(map[string]string),
+			Scripts:  make(map[string]map[string]string),
+			Started:  h.Started,
+			Finished: h.Finished,
+		}
+		for p := range h.Ports {
+			c.Open = append(c.Open, p)
+			if h.Filtered.Has(p) {
+				c.Filtered = append(c.Filtered, p)
+			}
+		}
+		nsight.SortPorts(c.Open)
+		nsight.SortPorts(c.Filtered)
+		for p, b := range h.Banners {
+			c.Banners[p.String()] = b
+		}
+		for p, r := range h.Reasons {
+			c.Reasons[p.String()] = r
+		}
+		for p, scripts := range h.Scripts {
+			key := p.String()
+			if p == nsight.HostScripts {
+				key = "host"
+			}
+			c.Scripts[key] = scripts
+		}
+		entry.Hosts[addr] = c
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
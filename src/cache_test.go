@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheOptIn(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir) // Linux and the BSDs
+	t.Setenv("HOME", dir)           // macOS
+	t.Setenv("LocalAppData", dir)   // Windows
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	entries := func() int {
+		found, _ := os.ReadDir(filepath.Join(cacheDir, "nsight"))
+		return len(found)
+	}
+
+	_, uncached, _ := runCLI(t, "testdata/dc.nmap")
+	if n := entries(); n != 0 {
+		t.Fatalf("wrote %d cache entries without --cache", n)
+	}
+	for i := 0; i < 2; i++ { // save, then load
+		_, out, _ := runCLI(t, "--cache", "testdata/dc.nmap")
+		if out != uncached {
+			t.Errorf("run %d with --cache:\n%s\nwant:\n%s", i+1, out, uncached)
+		}
+		if n := entries(); n != 1 {
+			t.Fatalf("want 1 cache entry after run %d with --cache, got %d", i+1, n)
+		}
+	}
+	if _, _, errOut := runCLI(t, "--cache", "--no-cache", "testdata/reason.nmap"); entries() != 1 {
+		t.Errorf("--no-cache did not override --cache: %s", errOut)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// diffScans compares the signatures and open ports of two parsed scans host
+// by host and prints what changed. It reports whether anything did.
+func diffScans(before, after map[string]*nsight.Host, sigs []nsight.Signature, minConfidence float64) bool {
+	all := make(map[string]*nsight.Host)
+	for host := range before {
+		all[host] = nil
+	}
+	for host := range after {
+		all[host] = nil
+	}
+
+	changed := false
+	for _, host := range nsight.SortedHosts(all) {
+		old, cur := before[host], after[host]
+		if old == nil {
+			old = nsight.NewHost()
+		}
+		if cur == nil {
+			cur = nsight.NewHost()
+		}
+		oldNames := matchedNames(matchHost(old, sigs, minConfidence))
+		curNames := matchedNames(matchHost(cur, sigs, minConfidence))
+		appeared, gone := onlyIn(curNames, oldNames), onlyIn(oldNames, curNames)
+		opened, closed := portsOnlyIn(cur, old), portsOnlyIn(old, cur)
+		if len(appeared)+len(gone)+len(opened)+len(closed) == 0 {
+			continue
+		}
+		changed = true
+
+		label := host
+		if label == "" {
+			label = "(all ports)"
+		}
+		note := ""
+		switch {
+		case before[host] == nil:
+			note = " " + style("(new host)", green, false, false)
+		case after[host] == nil:
+			note = " " + style("(gone)", red, false, false)
+		}
+		fmt.Fprintln(stdout, style("Host "+label, "", true, false)+note)
+		for _, name := range appeared {
+			fmt.Fprintln(stdout, style("+ Possible "+name+" detected", green, true, false))
+		}
+		for _, name := range gone {
+			fmt.Fprintln(stdout, style("- Possible "+name+" no longer detected", red, false, false))
+		}
+		if len(opened) > 0 {
+			fmt.Fprintf(stdout, "  ports opened: %s\n", joinPorts(opened, green, true, false))
+		}
+		if len(closed) > 0 {
+			fmt.Fprintf(stdout, "  ports closed: %s\n", joinPorts(closed, "", false, true))
+		}
+		fmt.Fprintln(stdout)
+	}
+	if !changed {
+		fmt.Fprintln(stdout, style("No changes between scans.", yellow, false, false))
+	}
+	return changed
+}
+
+func matchedNames(matches []nsight.Result) []string {
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.Signature
+	}
+	return names
+}
+
+// onlyIn returns the names in a that are not in b, keeping a's order.
+func onlyIn(a, b []string) []string {
+	seen := make(map[string]bool, len(b))
+	for _, name := range b {
+		seen[name] = true
+	}
+	var out []string
+	for _, name := range a {
+		if !seen[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// portsOnlyIn returns the open ports of a that b does not have.
+func portsOnlyIn(a, b *nsight.Host) []nsight.Port {
+	var out []nsight.Port
+	for p := range a.Ports {
+		if !b.Ports.Has(p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
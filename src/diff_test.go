@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffUsesReportPipeline(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		want, not []string
+	}{
+		{
+			name: "superseded",
+			want: []string{"+ Possible Active Directory Domain Controller detected"},
+			not:  []string{"SMB / NetBIOS file share"},
+		},
+		{
+			name: "show-all",
+			args: []string{"--show-all"},
+			want: []string{"+ Possible SMB / NetBIOS file share detected"},
+		},
+		{
+			name: "min-severity",
+			args: []string{"--min-severity", "high"},
+			want: []string{"+ Possible Redis detected"},
+			not:  []string{"SNMP agent", "PostgreSQL"},
+		},
+		{
+			name: "exclude",
+			args: []string{"--exclude", "redis"},
+			want: []string{"+ Possible PostgreSQL detected"},
+			not:  []string{"Redis"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(append([]string{"--diff"}, tt.args...), "testdata/ssh.nmap", "testdata/dc.nmap")
+			code, out, errOut := runCLI(t, args...)
+			if code != exitMatch {
+				t.Fatalf("exit %d, stderr %q", code, errOut)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("want %q in\n%s", s, out)
+				}
+			}
+			for _, s := range tt.not {
+				if strings.Contains(out, s) {
+					t.Errorf("want no %q in\n%s", s, out)
+				}
+			}
+		})
+	}
+}
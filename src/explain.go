@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// explainHost prints the --explain trace for h: every signature that had at
+// least one of its ports open, with what was found and what was missing.
+func explainHost(h *nsight.Host, sigs []nsight.Signature) {
+	if h == nil || len(h.Ports) == 0 {
+		return
+	}
+	var relevant []nsight.Explanation
+	for _, sig := range sigs {
+		if e := nsight.Explain(h, sig); e.Relevant() {
+			relevant = append(relevant, e)
+		}
+	}
+	if len(relevant) == 0 {
+		return
+	}
+	fmt.Fprintln(stdout, style("Explanation", "", true, false))
+	for _, e := range relevant {
+		explainSignature(h, e)
+	}
+	fmt.Fprintln(stdout)
+}
+
+// explainSignature prints the trace for one signature: whether it matched,
+// the ports found and missing in each role and, for a match, its notes.
+func explainSignature(h *nsight.Host, e nsight.Explanation) {
+	if e.Matched {
+		fmt.Fprintf(stdout, "  %s %s\n", style("✓", green, true, false), e.Signature)
+	} else {
+		fmt.Fprintf(stdout, "  %s %s: %s\n", style("✗", red, true, false), e.Signature, strings.Join(e.Reasons(), "; "))
+	}
+	explainLine(h, "required open", e.RequiredPresent, e.RequiredMissing)
+	for _, g := range e.AnyOf {
+		explainLine(h, groupLabel(g)+" open", g.Present, nil)
+	}
+	explainLine(h, "optional open", e.OptionalPresent, e.OptionalMissing)
+	if !e.Matched {
+		return
+	}
+	explainScore(e)
+	if e.Notes != "" {
+		fmt.Fprintf(stdout, "      note: %s\n", e.Notes)
+	}
+	for _, ref := range e.References {
+		fmt.Fprintf(stdout, "      see: %s\n", ref)
+	}
+}
+
+// explainScore prints how each port fed the confidence score of a match, one
+// row per port, and the total.
+func explainScore(e nsight.Explanation) {
+	if len(e.Contributions) == 0 {
+		return
+	}
+	roleWidth := len("role")
+	for _, c := range e.Contributions {
+		roleWidth = max(roleWidth, len(c.Role))
+	}
+	fmt.Fprintln(stdout, style(fmt.Sprintf("      %-9s %-*s %-7s %s", "port", roleWidth, "role", "present", "weight"), "", false, true))
+	seen, total := 0, 0
+	for _, c := range e.Contributions {
+		present := style(fmt.Sprintf("%-7s", "no"), "", false, true)
+		if c.Present {
+			present = style(fmt.Sprintf("%-7s", "yes"), green, false, false)
+			seen += c.Weight
+		}
+		total += c.Weight
+		fmt.Fprintf(stdout, "      %-9s %-*s %s %d\n", c.Port, roleWidth, c.Role, present, c.Weight)
+	}
+	fmt.Fprintf(stdout, "      confidence %.2f = %d of %d weight present\n", e.Confidence(), seen, total)
+}
+
+// explainLine prints "label: present; missing: ..." for one port role,
+// skipping roles the signature doesn't use. Open ports carry nmap's --reason
+// where the scan recorded one, e.g. "445 (syn-ack ttl 127)".
+func explainLine(h *nsight.Host, label string, present, missing []nsight.Port) {
+	if len(present) == 0 && len(missing) == 0 {
+		return
+	}
+	line := "none"
+	if len(present) > 0 {
+		parts := make([]string, len(present))
+		for i, p := range present {
+			parts[i] = style(p.String(), green, false, false)
+			if r := h.Reason(p); r != "" {
+				parts[i] += " (" + r + ")"
+			}
+		}
+		line = strings.Join(parts, ", ")
+	}
+	if len(missing) > 0 {
+		line += "; missing " + joinPorts(missing, "", false, true)
+	}
+	fmt.Fprintf(stdout, "      %s: %s\n", label, line)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplainReasonsForUntypedPorts(t *testing.T) {
+	// "445,3389" leaves Proto empty, which the host's reasons key as tcp.
+	sigs := filepath.Join(t.TempDir(), "sigs.json")
+	if err := os.WriteFile(sigs, []byte(`[{"name": "RDP file server", "required": "445,3389"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, out, errOut := runCLI(t, "--signatures-only", "--signatures", sigs, "--explain", "testdata/reason.nmap")
+	want := "required open: 445 (syn-ack ttl 127), 3389 (syn-ack ttl 127)"
+	if !strings.Contains(out, want) {
+		t.Errorf("want %q in\n%s%s", want, out, errOut)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// fetchScan downloads a scan from an http(s):// URL and parses the body,
+// failing on anything but 200 OK. --timeout bounds the whole request.
+func fetchScan(url string, opts nsight.ParseOptions) (map[string]*nsight.Host, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nsight.ParseNmapReaderWith(resp.Body, opts)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// printJSON writes findings and the run totals as an indented JSON object.
+// For a run over several hosts or files, given as reports, the object also
+// holds the scan's metadata and a per-host breakdown.
+func printJSON(findings []finding, totals summary, scan scanInfo, reports []hostReport) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if reports == nil {
+		return enc.Encode(struct {
+			Findings []finding `json:"findings"`
+			Summary  summary   `json:"summary"`
+		}{findings, totals})
+	}
+	return enc.Encode(struct {
+		Scan     scanInfo     `json:"scan"`
+		Hosts    []hostReport `json:"hosts"`
+		Findings []finding    `json:"findings"`
+		Summary  summary      `json:"summary"`
+	}{scan, reports, findings, totals})
+}
+
+// printCSV writes one row per finding for spreadsheet import. Ports within a
+// cell are joined with semicolons so the cell needs no quoting.
+func printCSV(findings []finding) error {
+	w := csv.NewWriter(stdout)
+	w.Write([]string{"host", "signature", "required_present", "optional_present", "optional_missing", "confidence", "any_of_present"})
+	for _, f := range findings {
+		w.Write([]string{
+			f.Host,
+			f.Signature,
+			csvPorts(f.RequiredPresent),
+			csvPorts(f.OptionalPresent),
+			csvPorts(f.OptionalMissing),
+			strconv.FormatFloat(f.Confidence, 'f', 2, 64),
+			csvPorts(f.AnyOfPresent()),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func csvPorts(ports []nsight.Port) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ";")
+}
+
+// printMarkdown writes findings as a report suitable for pasting into a
+// write-up: a summary table followed by one section per finding.
+func printMarkdown(findings []finding) {
+	fmt.Fprintln(stdout, "# nsight report")
+	fmt.Fprintln(stdout)
+	if len(findings) == 0 {
+		fmt.Fprintln(stdout, "No composite service signatures recognised.")
+		return
+	}
+	fmt.Fprintf(stdout, "%d composite service(s) identified.\n\n", len(findings))
+	fmt.Fprintln(stdout, "| Signature | Location | Confidence |")
+	fmt.Fprintln(stdout, "|---|---|---|")
+	for _, f := range findings {
+		fmt.Fprintf(stdout, "| %s | %s | %.2f |\n", mdEscape(f.Signature), mdEscape(location(f)), f.Confidence)
+	}
+	for _, f := range findings {
+		fmt.Fprintln(stdout)
+		heading := f.Signature
+		if loc := location(f); loc != "" {
+			heading += " (" + loc + ")"
+		}
+		fmt.Fprintf(stdout, "## %s\n\n", heading)
+		if len(f.RequiredPresent) > 0 {
+			fmt.Fprintf(stdout, "- **Required** (present): %s\n", portList(f.RequiredPresent))
+		}
+		for _, g := range f.AnyOf {
+			fmt.Fprintf(stdout, "- **%s**: %s present\n", mdEscape(groupLabel(g)), portList(g.Present))
+		}
+		if len(f.OptionalPresent) > 0 {
+			fmt.Fprintf(stdout, "- **Optional** (present): %s\n", portList(f.OptionalPresent))
+		}
+		if len(f.OptionalMissing) > 0 {
+			fmt.Fprintf(stdout, "- **Optional** (missing): %s\n", portList(f.OptionalMissing))
+		}
+		fmt.Fprintf(stdout, "- **Severity**: %s\n", f.Severity)
+		fmt.Fprintf(stdout, "- **Confidence**: %.2f\n", f.Confidence)
+		if f.Notes != "" {
+			fmt.Fprintf(stdout, "- **Notes**: %s\n", f.Notes)
+		}
+		for _, ref := range f.References {
+			fmt.Fprintf(stdout, "- **Reference**: <%s>\n", ref)
+		}
+	}
+}
+
+// location describes where a finding came from as "file host", omitting
+// whichever parts are unknown.
+func location(f finding) string {
+	return strings.TrimSpace(f.File + " " + f.Host)
+}
+
+// mdEscape keeps pipes in names from breaking table cells.
+func mdEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCSVAnyOfPorts(t *testing.T) {
+	code, out, errOut := runCLI(t, "--format", "csv", "testdata/dc.nmap")
+	if code != exitMatch {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	for _, row := range rows[1:] {
+		if row[col["signature"]] != "Windows remote-admin surface" {
+			continue
+		}
+		if got := row[col["any_of_present"]]; got != "135;445;5985" {
+			t.Errorf("any_of_present = %q, want 135;445;5985", got)
+		}
+		return
+	}
+	t.Fatalf("no Windows remote-admin surface row in\n%s", out)
+}
+
+func TestJSONShape(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		keys []string
+	}{
+		{"one host", []string{"--host", "10.0.0.9", "testdata/dc.nmap"}, []string{"findings", "summary"}},
+		{"several hosts", []string{"testdata/dc.nmap"}, []string{"findings", "hosts", "scan", "summary"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out, errOut := runCLI(t, append([]string{"--json"}, tt.args...)...)
+			var doc map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatalf("output is not a JSON object: %v\n%s%s", err, out, errOut)
+			}
+			var keys []string
+			for k := range doc {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("keys %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// hostFilter holds the --host values: addresses and CIDR ranges a host must
+// fall in to be processed. An empty filter keeps every host.
+type hostFilter []netip.Prefix
+
+func (f *hostFilter) String() string {
+	parts := make([]string, len(*f))
+	for i, p := range *f {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Set accepts a single address ("10.0.0.5") or a range ("10.0.0.0/24").
+func (f *hostFilter) Set(v string) error {
+	if p, err := netip.ParsePrefix(v); err == nil {
+		*f = append(*f, p.Masked())
+		return nil
+	}
+	a, err := netip.ParseAddr(v)
+	if err != nil {
+		return fmt.Errorf("%q is not an IP address or CIDR range", v)
+	}
+	*f = append(*f, netip.PrefixFrom(a.WithZone(""), a.BitLen()))
+	return nil
+}
+
+// keeps reports whether host passes the filter. Hosts without an address,
+// such as ports listed before any scan report, never do.
+func (f hostFilter) keeps(host string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	a, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	a = a.WithZone("").Unmap()
+	for _, p := range f {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply drops the hosts the filter doesn't keep.
+func (f hostFilter) apply(hosts map[string]*nsight.Host) map[string]*nsight.Host {
+	if len(f) == 0 {
+		return hosts
+	}
+	for host := range hosts {
+		if !f.keeps(host) {
+			delete(hosts, host)
+		}
+	}
+	return hosts
+}
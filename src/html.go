@@ -0,0 +1,97 @@
+package main
+
+import (
+	"html/template"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// htmlSection is one collapsible block of the HTML report.
+type htmlSection struct {
+	Location string
+	Findings []finding
+}
+
+// htmlChip is a port badge; Kind picks its colour.
+type htmlChip struct {
+	Port nsight.Port
+	Kind string // required, optional, missing
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"chips": chips,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>nsight report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+details { border: 1px solid #ccc; border-radius: 6px; margin: 0 0 1em; padding: 0.5em 1em; }
+summary { cursor: pointer; font-weight: bold; }
+h3 { margin: 1em 0 0.3em; font-size: 1em; }
+.category { color: #777; font-weight: normal; }
+.confidence { color: #777; font-size: 0.9em; }
+.chip { display: inline-block; border-radius: 1em; padding: 0.1em 0.6em; margin: 0.1em; font-family: monospace; }
+.required { background: #c8e6c9; }
+.optional { background: #bbdefb; }
+.missing { background: #eee; color: #888; text-decoration: line-through; }
+.legend { margin-bottom: 1.5em; }
+</style>
+</head>
+<body>
+<h1>nsight report</h1>
+{{- if not .}}
+<p>No composite service signatures recognised.</p>
+{{- else}}
+<p class="legend"><span class="chip required">required</span><span class="chip optional">optional, open</span><span class="chip missing">not open</span></p>
+{{- range .}}
+<details open>
+<summary>{{if .Location}}{{.Location}}{{else}}All hosts{{end}} ({{len .Findings}})</summary>
+{{- range .Findings}}
+<h3>{{.Signature}}{{if .Category}} <span class="category">{{.Category}}</span>{{end}}</h3>
+<div>{{range chips .Result}}<span class="chip {{.Kind}}">{{.Port}}</span>{{end}}</div>
+<div class="confidence">confidence {{printf "%.2f" .Confidence}}</div>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// chips lists the ports of a result as badges: required and open group
+// ports first, then optional ones that were open, then those that weren't.
+func chips(r nsight.Result) []htmlChip {
+	var out []htmlChip
+	add := func(ports []nsight.Port, kind string) {
+		for _, p := range ports {
+			out = append(out, htmlChip{p, kind})
+		}
+	}
+	add(r.RequiredPresent, "required")
+	for _, g := range r.AnyOf {
+		add(g.Present, "required")
+	}
+	add(r.OptionalPresent, "optional")
+	for _, g := range r.AnyOf {
+		add(g.Missing, "missing")
+	}
+	add(r.OptionalMissing, "missing")
+	return out
+}
+
+// printHTML writes findings as a self-contained HTML page with one
+// collapsible section per host, in the order the findings were produced.
+func printHTML(findings []finding) error {
+	var sections []htmlSection
+	for _, f := range findings {
+		loc := location(f)
+		if n := len(sections); n > 0 && sections[n-1].Location == loc {
+			sections[n-1].Findings = append(sections[n-1].Findings, f)
+			continue
+		}
+		sections = append(sections, htmlSection{Location: loc, Findings: []finding{f}})
+	}
+	return htmlReport.Execute(stdout, sections)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// liveScans holds the XML of the nmap runs --scan made, by the name they are
+// reported under. readScan reads them in place of a file.
+var liveScans = map[string][]byte{}
+
+// runNmap scans targets with nmap, writing XML to stdout, and returns the
+// name to pass on as an input path. Without --nmap-args it runs a version
+// scan of the TCP ports sigs use; with them, those arguments replace the
+// defaults. nmap's own progress and errors go to stderr.
+func runNmap(targets []string, args string, sigs []nsight.Signature) (string, error) {
+	nmap, err := exec.LookPath("nmap")
+	if err != nil {
+		return "", fmt.Errorf("--scan needs nmap in PATH")
+	}
+	argv := strings.Fields(args)
+	if args == "" {
+		argv = []string{"-sV", "-T4", "-p", signaturePorts(sigs)}
+	}
+	argv = append(append(argv, "-oX", "-"), targets...)
+	debugf("running %s %s", nmap, strings.Join(argv, " "))
+	var out bytes.Buffer
+	cmd := exec.Command(nmap, argv...)
+	cmd.Stdout, cmd.Stderr = &out, stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("nmap: %v", err)
+	}
+	name := "nmap " + strings.Join(targets, " ")
+	liveScans[name] = out.Bytes()
+	return name, nil
+}
+
+// signaturePorts lists, in nmap's -p syntax, every TCP port sigs mention.
+// UDP ports are left out: scanning them needs -sU, root and a lot of time.
+func signaturePorts(sigs []nsight.Signature) string {
+	set := make(nsight.PortSet)
+	for _, sig := range sigs {
+		ports := append(append(append([]nsight.Port{}, sig.Required...), sig.Optional...), sig.Forbidden...)
+		for _, g := range sig.AnyOf {
+			ports = append(ports, g.Ports...)
+		}
+		for _, p := range ports {
+			if portProto(p) == "" {
+				set.Add(p)
+			}
+		}
+	}
+	ports := make([]nsight.Port, 0, len(set))
+	for p := range set {
+		ports = append(ports, p)
+	}
+	nsight.SortPorts(ports)
+	return strings.ReplaceAll(strings.Join(portRuns(ports), ","), " ", "")
+}
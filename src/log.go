@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+)
+
+// logLevel orders diagnostics by importance. Only messages at or above
+// minLogLevel reach stderr; stdout is kept for results.
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelWarn
+	levelError
+)
+
+// minLogLevel is warn by default, debug under --verbose and error under
+// --quiet.
+var minLogLevel = levelWarn
+
+var levelPrefix = map[logLevel]string{
+	levelDebug: "nsight: debug: ",
+	levelWarn:  "nsight: warning: ",
+	levelError: "nsight: ",
+}
+
+func logf(level logLevel, format string, args ...any) {
+	if level < minLogLevel {
+		return
+	}
+	fmt.Fprintf(stderr, levelPrefix[level]+format+"\n", args...)
+}
+
+// debugf traces what nsight is doing, for --verbose.
+func debugf(format string, args ...any) { logf(levelDebug, format, args...) }
+
+// warnf prints a non-fatal diagnostic, such as a skipped line.
+func warnf(format string, args ...any) { logf(levelWarn, format, args...) }
+
+// errorf prints why nsight is about to fail. Errors are never filtered.
+func errorf(format string, args ...any) { logf(levelError, format, args...) }
@@ -1,28 +1,79 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"net/netip"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
 )
 
 // ANSI style fragments
 const (
-	bold   = "\033[1m"
-	green  = "\033[32m"
-	yellow = "\033[33m"
-	cyan   = "\033[36m"
-	faint  = "\033[2m"
-	reset  = "\033[0m"
+	bold    = "\033[1m"
+	green   = "\033[32m"
+	yellow  = "\033[33m"
+	cyan    = "\033[36m"
+	red     = "\033[31m"
+	magenta = "\033[35m"
+	faint   = "\033[2m"
+	reset   = "\033[0m"
+)
+
+// stdout and stderr receive the report and diagnostics. run sets them, and
+// --output points stdout at a file.
+var stdout, stderr io.Writer = os.Stdout, os.Stderr
+
+// Build metadata, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
 )
 
-var noColor bool // set by flag or NO_COLOR env var
+var (
+	noColor         bool                        // resolved from --color, NO_COLOR and the output format
+	showBanners     bool                        // print -sV service text under each match
+	showNearMisses  bool                        // also report signatures missing a few required ports
+	quiet           bool                        // print bare signature names, one per line
+	countOnly       bool                        // print only the number of matches
+	strict          bool                        // warn about skipped port lines, reject non-nmap input
+	includeFiltered bool                        // count open|filtered ports as open
+	explain         bool                        // trace each signature's decision after the report
+	fetchTimeout    time.Duration               // for http(s):// inputs
+	showAll         bool                        // keep matches a stronger match supersedes
+	minSeverity     nsight.Severity             // hide matches below this
+	verbose         bool                        // debug diagnostics; with --dir, also files with no matches
+	outputPath      string                      // --output: write the report here instead of stdout
+	onlyHosts       hostFilter                  // --host: process only these addresses and ranges
+	ignoredPorts    portFilter                  // --ignore-port: treat these ports as closed
+	bestOnly        bool                        // --mode best: keep only the strongest match on each host
+	weighted        map[string]nsight.Signature // --sort-by-weight: signatures by name, for their port weights
+	useCache        bool                        // --cache: reuse earlier parses from the cache directory and save new ones
+	approved        baseline                    // --baseline: ports and signatures expected on each host
+	since           time.Time                   // --since: skip hosts scanned before this; with --diff, the scan to compare from
+)
+
+// Port role colours, shared by the report and its legend.
+const (
+	requiredColour = green
+	optionalColour = yellow
+)
+
+// nearMissThreshold is the share of required ports a failed signature needs
+// before --show-near-misses reports it.
+const nearMissThreshold = 0.6
 
 // style applies colour + bold (if colour provided) or faint.
 func style(text string, colour string, boldOn bool, faintOn bool) string {
@@ -45,160 +96,1390 @@ func style(text string, colour string, boldOn bool, faintOn bool) string {
 	return sb.String()
 }
 
-// joinPorts produces "139, 445" with per‑port styling.
-func joinPorts(ports []int, colour string, boldOn bool, faintOn bool) string {
-	sort.Ints(ports)
-	parts := make([]string, len(ports))
-	for i, p := range ports {
-		parts[i] = style(strconv.Itoa(p), colour, boldOn, faintOn)
+// joinPorts produces "139, 445" with per‑port styling, folding runs of
+// consecutive ports into ranges such as "50001-50050".
+func joinPorts(ports []nsight.Port, colour string, boldOn bool, faintOn bool) string {
+	nsight.SortPorts(ports)
+	return joinSorted(ports, colour, boldOn, faintOn)
+}
+
+// joinByWeight is joinPorts with the ports sig weighs most first, for
+// --sort-by-weight; equal weights stay in numeric order.
+func joinByWeight(ports []nsight.Port, sig nsight.Signature, colour string, boldOn bool, faintOn bool) string {
+	nsight.SortPorts(ports)
+	sort.SliceStable(ports, func(i, j int) bool {
+		return sig.Weight(ports[i]) > sig.Weight(ports[j])
+	})
+	return joinSorted(ports, colour, boldOn, faintOn)
+}
+
+func joinSorted(ports []nsight.Port, colour string, boldOn bool, faintOn bool) string {
+	parts := portRuns(ports)
+	for i, part := range parts {
+		parts[i] = style(part, colour, boldOn, faintOn)
 	}
 	return strings.Join(parts, ", ")
 }
 
-// Signature for a composite service.
-type Signature struct {
-	Name     string
-	Required []int
-	Optional []int
+// minPortRun is the shortest run of consecutive ports shown as a range.
+const minPortRun = 3
+
+// portRuns renders sorted ports one by one, except that minPortRun or more
+// consecutive ports of the same protocol become "8000-8002" (or
+// "161-163/udp").
+func portRuns(ports []nsight.Port) []string {
+	var out []string
+	for i := 0; i < len(ports); {
+		j := i + 1
+		for j < len(ports) && portProto(ports[j]) == portProto(ports[i]) && ports[j].Number == ports[j-1].Number+1 {
+			j++
+		}
+		if j-i < minPortRun {
+			for _, p := range ports[i:j] {
+				out = append(out, p.String())
+			}
+		} else {
+			last := ports[j-1]
+			out = append(out, strconv.Itoa(ports[i].Number)+"-"+last.String())
+		}
+		i = j
+	}
+	return out
+}
+
+// portProto is the protocol suffix of p's String form: "" for TCP.
+func portProto(p nsight.Port) string {
+	_, proto, _ := strings.Cut(p.String(), "/")
+	return proto
+}
+
+// pathList collects the values of a repeatable flag.
+type pathList []string
+
+func (l *pathList) String() string { return strings.Join(*l, ", ") }
+
+func (l *pathList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// finding is a match tagged with where it was found.
+type finding struct {
+	File string `json:"file,omitempty"`
+	Host string `json:"host,omitempty"`
+	// ScanStarted and ScanFinished are when the scan reporting the host ran,
+	// if its output recorded that.
+	ScanStarted  *time.Time `json:"scanStarted,omitempty"`
+	ScanFinished *time.Time `json:"scanFinished,omitempty"`
+	nsight.Result
+}
+
+// newFinding returns m as found on host h.
+func newFinding(file, host string, h *nsight.Host, m nsight.Result) finding {
+	f := finding{File: file, Host: host, Result: m}
+	if !h.Started.IsZero() {
+		f.ScanStarted = &h.Started
+	}
+	if !h.Finished.IsZero() {
+		f.ScanFinished = &h.Finished
+	}
+	return f
+}
+
+// hostReport is one host's entry in the JSON "hosts" list. Every list is
+// non-nil so it encodes as [] rather than null.
+type hostReport struct {
+	File        string          `json:"file,omitempty"`
+	Host        string          `json:"host"`
+	OpenPorts   []nsight.Port   `json:"openPorts"`
+	Matches     []nsight.Result `json:"matches"`
+	Unexplained []nsight.Port   `json:"unexplainedPorts"`
+}
+
+func newHostReport(file, host string, h *nsight.Host, matches []nsight.Result, left []nsight.Port) hostReport {
+	r := hostReport{File: file, Host: host, OpenPorts: []nsight.Port{}, Matches: []nsight.Result{}, Unexplained: []nsight.Port{}}
+	for p := range h.Ports {
+		r.OpenPorts = append(r.OpenPorts, p)
+	}
+	nsight.SortPorts(r.OpenPorts)
+	r.Matches = append(r.Matches, matches...)
+	r.Unexplained = append(r.Unexplained, left...)
+	return r
+}
+
+// scanInfo is the JSON "scan" object: the files a run read and the span of
+// time their scans covered.
+type scanInfo struct {
+	Files    []string   `json:"files"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// widen stretches the scan's time span to cover h's.
+func (s *scanInfo) widen(h *nsight.Host) {
+	if !h.Started.IsZero() && (s.Started == nil || h.Started.Before(*s.Started)) {
+		s.Started = &h.Started
+	}
+	if !h.Finished.IsZero() && (s.Finished == nil || h.Finished.After(*s.Finished)) {
+		s.Finished = &h.Finished
+	}
+}
+
+// summary totals a run for the footer and the JSON summary object.
+type summary struct {
+	Hosts      int `json:"hosts"`
+	OpenPorts  int `json:"openPorts"`
+	Matches    int `json:"matches"`
+	Signatures int `json:"signatures"` // distinct signature names matched
+	// Unexplained counts open ports no match on their host accounted for.
+	Unexplained int `json:"unexplainedPorts"`
+	seen        map[string]bool
+	hosts       map[string]bool            // host keys recorded, so a host in several files counts once
+	portHosts   map[nsight.Port]int        // hosts each port was open on, for --stats
+	sigHosts    map[string]map[string]bool // hosts each signature matched on, for --summary
+}
+
+// countPorts adds the open ports of every host in hosts to the --stats
+// histogram. It is kept apart from record so --merge still counts per host.
+func (s *summary) countPorts(hosts map[string]*nsight.Host) {
+	if s.portHosts == nil {
+		s.portHosts = make(map[nsight.Port]int)
+	}
+	for _, h := range hosts {
+		for p := range h.Ports {
+			s.portHosts[p]++
+		}
+	}
+}
+
+// rollUp notes which signatures matched on host for --summary. A host read
+// from several files counts once.
+func (s *summary) rollUp(host string, matches []nsight.Result) {
+	if s.sigHosts == nil {
+		s.sigHosts = make(map[string]map[string]bool)
+	}
+	for _, m := range matches {
+		if s.sigHosts[m.Signature] == nil {
+			s.sigHosts[m.Signature] = make(map[string]bool)
+		}
+		s.sigHosts[m.Signature][host] = true
+	}
+}
+
+// record adds one host, the matches found on it and its unexplained ports.
+// Hosts counts distinct keys, from hostKey.
+func (s *summary) record(key string, h *nsight.Host, matches []nsight.Result, unexplained []nsight.Port) {
+	if s.seen == nil {
+		s.seen, s.hosts = make(map[string]bool), make(map[string]bool)
+	}
+	s.hosts[key] = true
+	s.Hosts = len(s.hosts)
+	s.OpenPorts += len(h.Ports)
+	s.Matches += len(matches)
+	s.Unexplained += len(unexplained)
+	for _, m := range matches {
+		if !s.seen[m.Signature] {
+			s.seen[m.Signature] = true
+			s.Signatures++
+		}
+	}
+}
+
+// hostKey identifies a host across input files for the totals: its address,
+// or for output that gave none, the file it came from.
+func hostKey(path, host string) string {
+	if host == "" {
+		return "file " + path
+	}
+	return host
+}
+
+// Exit codes, documented in the usage text.
+const (
+	exitMatch   = 0 // at least one signature matched
+	exitNoMatch = 1 // nothing matched
+	exitError   = 2 // bad usage or no input could be parsed
+	exitFailOn  = 3 // a match tripped --fail-on or --fail-on-severity
+)
+
+func usage() {
+	fmt.Fprintln(stderr, "Usage: nsight [flags] <nmap -oN/-oX/-oG output file>... | -")
+	fmt.Fprintln(stderr)
+	flag.PrintDefaults()
+	fmt.Fprintln(stderr)
+	fmt.Fprintln(stderr, "Exit codes: 0 a signature matched, 1 nothing matched, 2 usage or parse error,")
+	fmt.Fprintln(stderr, "3 a match tripped --fail-on or --fail-on-severity (checked after 2, before 0).")
+	fmt.Fprintln(stderr, "With --diff: 0 the scans differ, 1 they don't.")
+	fmt.Fprintln(stderr, "With --validate-signatures: 0 the file is clean, 1 it has problems.")
 }
 
 func main() {
-	flag.BoolVar(&noColor, "no-color", false, "disable ANSI colour output")
-	flag.Parse()
-	if os.Getenv("NO_COLOR") != "" {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run is the whole command line, returning the exit code rather than
+// exiting: it parses args, writes the report to out and diagnostics to
+// errOut. State from an earlier call is reset, so tests can call it again.
+func run(args []string, out, errOut io.Writer) int {
+	stdout, stderr = out, errOut
+	flag.CommandLine = flag.NewFlagSet("nsight", flag.ContinueOnError)
+	flag.CommandLine.SetOutput(stderr)
+	flag.CommandLine.Usage = usage
+	minLogLevel = levelWarn
+	onlyHosts, ignoredPorts, weighted, approved, bestOnly, since = nil, nil, nil, nil, false, time.Time{}
+	liveScans, portLists = map[string][]byte{}, map[string]*nsight.Host{}
+	var merge, jsonOut, noCache, sigsOnly, list, showVersion, diffMode, showStats, watch, tuiMode, rollUp, printSchema, byWeight, byRarity bool
+	var baselinePath, sinceText string
+	var only, exclude, category, format, colorMode, lintPath, severity, dir, matchExpr, notExpr, tmplText, failOnExpr, failOnSeverity, mode, nmapArgs, portSpec string
+	var sigPaths, scanTargets pathList
+	var minConfidence float64
+	var jobs, statsTop int
+	flag.StringVar(&colorMode, "color", "auto", "colour `mode`: always, auto (only on a terminal) or never")
+	flag.BoolVar(&noColor, "no-color", false, "deprecated: same as --color never")
+	flag.BoolVar(&merge, "merge", false, "union ports from all hosts and input files before matching")
+	flag.StringVar(&format, "format", "text", "output `format`: text, json, markdown, html or csv")
+	flag.BoolVar(&jsonOut, "json", false, "shorthand for --format json")
+	flag.StringVar(&tmplText, "template", "", "render each match with a Go `template`, or a named one: syslog, oneline")
+	flag.StringVar(&outputPath, "output", "", "write the report to `file` instead of stdout")
+	flag.Var(&sigPaths, "signatures", "load extra signatures from a JSON or TOML `file`; repeatable (default $NSIGHT_SIGNATURES)")
+	flag.StringVar(&lintPath, "validate-signatures", "", "check a signature `file` for problems and exit without scanning")
+	flag.BoolVar(&printSchema, "print-schema", false, "print the JSON Schema for signature files and exit")
+	flag.BoolVar(&sigsOnly, "signatures-only", false, "use only the signatures from --signatures, not the built-ins")
+	flag.StringVar(&only, "only", "", "run only signatures whose name contains `text` (case-insensitive)")
+	flag.StringVar(&exclude, "exclude", "", "skip signatures whose name contains `text` (case-insensitive)")
+	flag.StringVar(&matchExpr, "match", "", "run only signatures whose name matches `regexp` (case-insensitive)")
+	flag.StringVar(&notExpr, "not", "", "skip signatures whose name matches `regexp` (case-insensitive)")
+	flag.StringVar(&category, "category", "", "run only signatures in this `category`, e.g. Databases")
+	flag.BoolVar(&showBanners, "banners", false, "show nmap -sV service/version text for matched ports")
+	flag.BoolVar(&byRarity, "rarity", false, "weight each port by how few signatures use it when scoring confidence")
+	flag.BoolVar(&byWeight, "sort-by-weight", false, "list a match's present optional ports most diagnostic (highest weight) first")
+	flag.BoolVar(&showVersion, "version", false, "print version information and exit")
+	flag.BoolVar(&showNearMisses, "show-near-misses", false, "also report signatures with most, but not all, required ports open")
+	flag.Var(&onlyHosts, "host", "process only this `address` or CIDR range, e.g. 10.0.0.0/24; repeatable")
+	flag.StringVar(&baselinePath, "baseline", "", "leave out the host:port and host:signature pairs listed in `file`, reporting only what differs")
+	flag.Var(&ignoredPorts, "ignore-port", "treat `ports` such as 443 or 8000-8100 as closed on every host; repeatable")
+	flag.Var(&scanTargets, "scan", "run nmap against `target` (an address, range or name) and report on the results; repeatable")
+	flag.StringVar(&portSpec, "ports", "", "report on a host with just these `ports` open, e.g. 22,80,443,161/udp, instead of a scan file")
+	flag.StringVar(&nmapArgs, "nmap-args", "", "with --scan, nmap `arguments` to use instead of a version scan of the signatures' TCP ports")
+	flag.StringVar(&dir, "dir", "", "also read every .nmap, .xml and .gnmap file (optionally .gz) under `directory`")
+	flag.BoolVar(&verbose, "verbose", false, "show the full report: weak matches, missing optional ports, near misses, unexplained ports, files where nothing matched and debug diagnostics")
+	flag.StringVar(&sinceText, "since", "", "skip hosts scanned before `time`, e.g. 2026-10-01 or 2026-10-01 09:00; with --diff, compare the last scan started by then with the newest")
+	flag.BoolVar(&tuiMode, "tui", false, "browse hosts and matches interactively (falls back to the report off a terminal)")
+	flag.BoolVar(&watch, "watch", false, "re-run whenever the scan files change, e.g. while nmap is still writing")
+	flag.BoolVar(&diffMode, "diff", false, "compare two scans: nsight --diff old new")
+	flag.BoolVar(&useCache, "cache", false, "reuse earlier parses of unchanged files, and save new ones, under the user cache directory")
+	flag.BoolVar(&noCache, "no-cache", false, "deprecated: the cache is off unless --cache is given")
+	flag.BoolVar(&strict, "strict", false, "warn about port lines that were not counted and reject files with no nmap output")
+	flag.BoolVar(&includeFiltered, "include-filtered", false, "count open|filtered ports (common in UDP scans) as open")
+	flag.BoolVar(&showStats, "stats", false, "after the report, list the most common open ports across all hosts")
+	flag.IntVar(&statsTop, "stats-top", 10, "how many ports --stats lists")
+	flag.BoolVar(&rollUp, "summary", false, "list each matched signature with the hosts it was found on, instead of a report per host")
+	flag.DurationVar(&fetchTimeout, "timeout", 30*time.Second, "give up fetching an http(s):// scan after this `duration`")
+	flag.StringVar(&failOnExpr, "fail-on", "", "exit 3 if a signature whose name matches `regexp` (case-insensitive) fires")
+	flag.StringVar(&failOnSeverity, "fail-on-severity", "", "exit 3 if a match at or above this `level` fires")
+	flag.StringVar(&severity, "min-severity", "info", "hide matches below this `level`: info, low, medium, high or critical")
+	flag.BoolVar(&showAll, "show-all", false, "also show matches superseded by a stronger one, e.g. SMB on a domain controller")
+	flag.BoolVar(&explain, "explain", false, "after each host, explain why signatures did or didn't match")
+	flag.BoolVar(&quiet, "quiet", false, "print only the names of matched signatures, one per line")
+	flag.BoolVar(&countOnly, "count-only", false, "print only the number of matches")
+	flag.BoolVar(&list, "list", false, "list the known signatures and exit")
+	flag.IntVar(&jobs, "jobs", runtime.GOMAXPROCS(0), "parse and match up to `n` files at once")
+	flag.Float64Var(&minConfidence, "min-confidence", 0, "hide matches scoring below this confidence (0-1)")
+	flag.StringVar(&mode, "mode", "all", "`mode`: all to report every match on a host, or best for only the highest-confidence one")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return exitError
+	}
+	if showVersion {
+		fmt.Fprintf(stdout, "nsight %s (commit %s, built %s)\n", version, commit, date)
+		return 0
+	}
+	if jsonOut {
+		format = "json"
+	}
+	if countOnly {
+		format, quiet = "text", true
+	}
+	switch {
+	case verbose:
+		minLogLevel = levelDebug
+	case quiet:
+		minLogLevel = levelError
+	}
+	var err error
+	if minSeverity, err = nsight.ParseSeverity(severity); err != nil {
+		errorf("--min-severity: %v", err)
+		return exitError
+	}
+	if sinceText != "" {
+		if since, err = parseSince(sinceText); err != nil {
+			errorf("--since: %v", err)
+			return exitError
+		}
+	}
+	filter := sigFilter{only: only, exclude: exclude, category: category}
+	if filter.match, err = nameRegexp(matchExpr); err != nil {
+		errorf("--match: %v", err)
+		return exitError
+	}
+	if filter.not, err = nameRegexp(notExpr); err != nil {
+		errorf("--not: %v", err)
+		return exitError
+	}
+	var gate failGate
+	if gate.name, err = nameRegexp(failOnExpr); err != nil {
+		errorf("--fail-on: %v", err)
+		return exitError
+	}
+	if failOnSeverity != "" {
+		gate.bySeverity = true
+		if gate.severity, err = nsight.ParseSeverity(failOnSeverity); err != nil {
+			errorf("--fail-on-severity: %v", err)
+			return exitError
+		}
+	}
+	switch mode {
+	case "all":
+	case "best":
+		bestOnly = true
+	default:
+		errorf("unknown --mode %q", mode)
+		return exitError
+	}
+	switch format {
+	case "text", "json", "markdown", "html", "csv":
+	default:
+		errorf("unknown --format %q", format)
+		return exitError
+	}
+	var tmpl *template.Template
+	if tmplText != "" {
+		if format != "text" || countOnly {
+			errorf("--template cannot be combined with --format, --json or --count-only")
+			return exitError
+		}
+		if tmpl, err = parseFindingTemplate(tmplText); err != nil {
+			errorf("--template: %v", err)
+			return exitError
+		}
+		format = "template"
+	}
+	if rollUp && (format != "text" || merge) {
+		errorf("--summary cannot be combined with --format, --json, --template or --merge")
+		return exitError
+	}
+	if baselinePath != "" {
+		if diffMode {
+			errorf("--baseline cannot be combined with --diff")
+			return exitError
+		}
+		if approved, err = loadBaseline(baselinePath); err != nil {
+			errorf("--baseline: %v", err)
+			return exitError
+		}
+		debugf("loaded %d baseline entries from %s", len(approved), baselinePath)
+	}
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			errorf("--output: %v", err)
+			return exitError
+		}
+		defer f.Close()
+		// Everything below writes the report to stdout; diagnostics stay
+		// on stderr. Colour under "auto" follows from the file not being a
+		// terminal.
+		stdout = f
+	}
+	text := format == "text"
+	useCache = useCache && !noCache
+	if noColor {
+		colorMode = "never"
+	}
+	switch colorMode {
+	case "always":
+		noColor = false
+	case "auto":
+		noColor = os.Getenv("NO_COLOR") != "" || !isTerminal(stdout)
+	case "never":
+		noColor = true
+	default:
+		errorf("unknown --color %q", colorMode)
+		return exitError
+	}
+	if !text || quiet {
 		noColor = true
 	}
 
-	if flag.NArg() != 1 {
-		fmt.Fprintln(os.Stderr, "Usage: nsight [--no-color] <nmap -oN output file>")
-		os.Exit(1)
+	if lintPath != "" {
+		return validateSignatures(lintPath)
+	}
+	if printSchema {
+		stdout.Write(nsight.SignatureSchema())
+		return 0
 	}
 
-	openPorts, err := parseNmap(flag.Arg(0))
-	if err != nil {
-		log.Fatalf("cannot parse %s: %v", flag.Arg(0), err)
+	sigs := nsight.Signatures()
+	if len(sigPaths) == 0 {
+		sigPaths = filepath.SplitList(os.Getenv("NSIGHT_SIGNATURES"))
+	}
+	if sigsOnly && len(sigPaths) == 0 {
+		errorf("--signatures-only requires --signatures")
+		return exitError
+	}
+	if sigsOnly {
+		sigs = nil
+	}
+	definedIn := make(map[string]string) // signature name -> file it came from
+	for _, path := range sigPaths {
+		custom, err := nsight.LoadSignatures(path)
+		if err != nil {
+			errorf("cannot load signatures: %v", err)
+			return exitError
+		}
+		debugf("loaded %d signature(s) from %s", len(custom), path)
+		for _, sig := range custom {
+			if prev, ok := definedIn[sig.Name]; ok && prev != path {
+				warnf("signature %q in %s overrides the one in %s", sig.Name, path, prev)
+			}
+			definedIn[sig.Name] = path
+		}
+		sigs = append(sigs, custom...)
+	}
+	sigs = nsight.Dedupe(sigs)
+	if sigs, err = nsight.ResolveBases(sigs); err != nil {
+		errorf("cannot load signatures: %v", err)
+		return exitError
+	}
+	for _, sig := range sigs {
+		for _, w := range nsight.Warnings(sig) {
+			warnf("signature %q: %s", sig.Name, w)
+		}
+	}
+	// Before the filters: a baseline may name signatures --only leaves out.
+	if err := approved.checkSignatures(baselinePath, sigs); err != nil {
+		errorf("--baseline: %v", err)
+		return exitError
+	}
+	if byRarity {
+		// Before the filters, so the weights reflect every signature.
+		sigs = nsight.WeightByRarity(sigs)
+	}
+	if filter != (sigFilter{}) {
+		if sigs = filterSignatures(sigs, filter); len(sigs) == 0 {
+			errorf("--only/--exclude/--match/--not/--category left no signatures to run")
+			return exitError
+		}
+	}
+	debugf("running %d signature(s)", len(sigs))
+	if byWeight {
+		weighted = make(map[string]nsight.Signature, len(sigs))
+		for _, sig := range sigs {
+			weighted[sig.Name] = sig
+		}
+	}
+	if list {
+		listSignatures(sigs)
+		return 0
 	}
 
-	if len(openPorts) == 0 {
-		fmt.Println(style("No open ports found.", yellow, false, false))
-		return
+	paths := flag.Args()
+	if dir != "" {
+		found, err := findScans(dir)
+		if err != nil {
+			errorf("--dir: %v", err)
+			return exitError
+		}
+		if len(found) == 0 {
+			errorf("no scan files found under %s", dir)
+			return exitError
+		}
+		paths = append(paths, found...)
+	}
+	if len(scanTargets) > 0 {
+		if diffMode || watch {
+			errorf("--scan cannot be combined with --diff or --watch")
+			return exitError
+		}
+		name, err := runNmap(scanTargets, nmapArgs, sigs)
+		if err != nil {
+			errorf("%v", err)
+			return exitError
+		}
+		paths = append(paths, name)
+	}
+	if portSpec != "" {
+		if watch {
+			errorf("--ports cannot be combined with --watch")
+			return exitError
+		}
+		name, err := addPortList(portSpec)
+		if err != nil {
+			errorf("--ports: %v", err)
+			return exitError
+		}
+		paths = append(paths, name)
+	}
+	if len(paths) == 0 && !stdinIsTTY() {
+		paths = []string{"-"}
+	}
+	if len(paths) == 0 {
+		usage()
+		return exitError
+	}
+
+	if diffMode {
+		return runDiff(paths, sigs, merge, minConfidence)
 	}
 
-	any := false
-	for _, sig := range knownSignatures() {
-		if hasAll(openPorts, sig.Required) {
-			any = true
-			header := style("▶", green, true, false)
-			service := style("Possible "+sig.Name+" detected", cyan, true, false)
-			fmt.Printf("%s %s: ", header, service)
+	cfg := runConfig{format: format, merge: merge, minConfidence: minConfidence, jobs: jobs, stats: showStats, statsTop: statsTop,
+		hideEmpty: dir != "" && !verbose, tmpl: tmpl, gate: gate, rollUp: rollUp}
+	if tuiMode && canRunTUI() {
+		return runTUI(paths, sigs, cfg)
+	}
+	if tuiMode {
+		debugf("--tui needs a terminal on stdin and stdout; printing the report instead")
+	}
+	if watch {
+		return watchFiles(paths, func() int { return analyse(paths, sigs, cfg) })
+	}
+	return analyse(paths, sigs, cfg)
+}
+
+// runConfig carries the flags analyse needs beyond the presentation globals.
+type runConfig struct {
+	format        string
+	merge         bool
+	minConfidence float64
+	jobs          int
+	stats         bool
+	statsTop      int
+	hideEmpty     bool               // leave out files where nothing matched
+	tmpl          *template.Template // for --template
+	gate          failGate
+	rollUp        bool // --summary: per-signature host lists in place of host reports
+}
 
-			fmt.Printf("Required ports %s are present",
-				joinPorts(sig.Required, green, true, false))
+// failGate holds --fail-on and --fail-on-severity.
+type failGate struct {
+	name       *regexp.Regexp // nil when --fail-on is unset
+	severity   nsight.Severity
+	bySeverity bool
+}
+
+// trips reports whether m should fail the run.
+func (g failGate) trips(m nsight.Result) bool {
+	return (g.name != nil && g.name.MatchString(m.Signature)) || (g.bySeverity && m.Severity >= g.severity)
+}
+
+// tripped reports whether any of matches trips the gate.
+func (g failGate) tripped(matches []nsight.Result) bool {
+	for _, m := range matches {
+		if g.trips(m) {
+			return true
+		}
+	}
+	return false
+}
 
-			if len(sig.Optional) > 0 {
-				present := presentOptional(openPorts, sig.Optional)
-				if len(present) > 0 {
-					fmt.Printf(", optional ports %s are also present",
-						joinPorts(present, yellow, true, false))
+// analyse parses, matches and reports on paths, returning the exit code.
+func analyse(paths []string, sigs []nsight.Signature, cfg runConfig) int {
+	text := cfg.format == "text"
+	findings := []finding{}
+	var totals summary
+	var reports []hostReport
+	scan := scanInfo{Files: append([]string{}, paths...)}
+	merged := nsight.NewHost()
+	parsed := 0
+	matched, failed := false, false
+	var match func(*nsight.Host) []nsight.Result
+	if !cfg.merge {
+		match = func(h *nsight.Host) []nsight.Result {
+			return matchHost(h, sigs, cfg.minConfidence)
+		}
+	}
+	scans := scanAll(paths, cfg.jobs, match)
+	if len(onlyHosts) > 0 {
+		kept := 0
+		for _, s := range scans {
+			kept += len(s.hosts)
+		}
+		if kept == 0 {
+			errorf("--host %s matched no hosts", onlyHosts.String())
+			return exitError
+		}
+	}
+	if !since.IsZero() {
+		kept := 0
+		for _, s := range scans {
+			kept += len(s.hosts)
+		}
+		if kept == 0 {
+			errorf("no hosts were scanned since %s", since.Format(sinceShown))
+			return exitError
+		}
+	}
+	if text && !quiet && !noColor && !cfg.rollUp {
+		printLegend()
+	}
+	for i, path := range paths {
+		hosts, err := scans[i].hosts, scans[i].err
+		for _, line := range scans[i].skipped {
+			warnf("%s: ignoring %q", path, line)
+		}
+		if err != nil && strict {
+			errorf("cannot parse %s: %v", path, err)
+			return exitError
+		}
+		if err != nil {
+			warnf("cannot parse %s: %v", path, err)
+			continue
+		}
+		parsed++
+		debugf("%s: %d host(s)", path, len(hosts))
+		if len(paths) > 1 && len(hosts) == 0 && !cfg.hideEmpty {
+			warnf("no open ports found in %s", path)
+		}
+		totals.countPorts(hosts)
+		if cfg.hideEmpty && !cfg.merge && !anyMatches(scans[i]) {
+			for host, h := range hosts {
+				totals.record(hostKey(path, host), h, nil, approved.ports(host, unexplained(h, sigs)))
+			}
+			continue
+		}
+
+		if cfg.merge {
+			for _, h := range hosts {
+				merged.Merge(h)
+			}
+			continue
+		}
+		if text && !quiet && len(paths) > 1 && !cfg.rollUp {
+			fmt.Fprintln(stdout, style("==> "+path+" <==", "", true, false))
+		}
+		if text && len(hosts) == 0 && !cfg.rollUp {
+			report(nil, nil, nil, nil, cfg.gate)
+		}
+		for _, host := range nsight.SortedHosts(hosts) {
+			matches := scans[i].matches[host]
+			matched = matched || len(matches) > 0
+			failed = failed || cfg.gate.tripped(matches)
+			left := approved.ports(host, unexplained(hosts[host], sigs))
+			totals.record(hostKey(path, host), hosts[host], matches, left)
+			if cfg.rollUp {
+				name := host
+				if name == "" {
+					name = path
 				}
-				missing := diff(sig.Optional, present)
-				if len(missing) > 0 {
-					fmt.Printf(", optional ports %s are missing",
-						joinPorts(missing, "", false, true))
+				totals.rollUp(name, matches)
+				continue
+			}
+			if !text {
+				file := ""
+				if len(paths) > 1 {
+					file = path
+				}
+				for _, m := range matches {
+					findings = append(findings, newFinding(file, host, hosts[host], m))
 				}
+				reports = append(reports, newHostReport(file, host, hosts[host], matches, left))
+				scan.widen(hosts[host])
+				continue
+			}
+			if host != "" && !quiet {
+				fmt.Fprintln(stdout, style("Host "+host, "", true, false))
+			}
+			report(hosts[host], matches, nearMisses(hosts[host], sigs), left, cfg.gate)
+			if explain && !quiet {
+				explainHost(hosts[host], sigs)
+			}
+		}
+	}
+	if parsed == 0 {
+		return exitError
+	}
+	if cfg.merge {
+		matches := approved.matches("", matchHost(merged, sigs, cfg.minConfidence))
+		matched = len(matches) > 0
+		failed = cfg.gate.tripped(matches)
+		left := approved.ports("", unexplained(merged, sigs))
+		totals.record("", merged, matches, left)
+		if text {
+			report(merged, matches, nearMisses(merged, sigs), left, cfg.gate)
+			if explain && !quiet {
+				explainHost(merged, sigs)
 			}
-			fmt.Printf("\n")
 		}
+		for _, m := range matches {
+			findings = append(findings, newFinding("", "", merged, m))
+		}
+		reports = append(reports, newHostReport("", "", merged, matches, left))
+		scan.widen(merged)
+	}
 
+	var err error
+	switch cfg.format {
+	case "json":
+		if len(paths) == 1 && len(reports) <= 1 {
+			reports = nil // a single host needs only the findings
+		}
+		err = printJSON(findings, totals, scan, reports)
+	case "markdown":
+		printMarkdown(findings)
+	case "html":
+		err = printHTML(findings)
+	case "csv":
+		err = printCSV(findings)
+	case "template":
+		err = printTemplate(findings, cfg.tmpl)
+	default:
+		if cfg.rollUp && !quiet {
+			printRollUp(totals)
+		}
+		if !quiet {
+			printSummary(totals)
+		}
+		if cfg.stats && !quiet {
+			printStats(totals, cfg.statsTop)
+		}
+		if countOnly {
+			fmt.Fprintln(stdout, totals.Matches)
+		}
 	}
+	if err != nil {
+		errorf("%v", err)
+		return exitError
+	}
+	if failed {
+		return exitFailOn
+	}
+	if !matched {
+		return exitNoMatch
+	}
+	return exitMatch
+}
 
-	if !any {
-		fmt.Println(style("No composite service signatures recognised.", yellow, false, false))
+// printSummary prints the footer line under the text report.
+func printSummary(s summary) {
+	line := fmt.Sprintf("%d composite service(s) identified across %d open port(s)", s.Matches, s.OpenPorts)
+	if s.Hosts > 1 {
+		line += fmt.Sprintf(" on %d hosts", s.Hosts)
+	}
+	if s.Signatures != s.Matches {
+		line += fmt.Sprintf(" (%d distinct signature(s))", s.Signatures)
 	}
+	fmt.Fprintln(stdout, style(line+".", "", true, false))
+}
 
-	fmt.Printf("\n")
+// printStats prints the top most common open ports with the number of hosts
+// each was open on, most common first.
+func printStats(s summary, top int) {
+	ports := make([]nsight.Port, 0, len(s.portHosts))
+	for p := range s.portHosts {
+		ports = append(ports, p)
+	}
+	nsight.SortPorts(ports)
+	sort.SliceStable(ports, func(i, j int) bool {
+		return s.portHosts[ports[i]] > s.portHosts[ports[j]]
+	})
+	if len(ports) > top {
+		ports = ports[:top]
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, style("Most common open ports", "", true, false))
+	for _, p := range ports {
+		fmt.Fprintf(stdout, "  %s %d host(s)\n", style(fmt.Sprintf("%-9s", p), cyan, true, false), s.portHosts[p])
+	}
 }
 
-// --- helpers -------------------------------------------------------------
+// printRollUp prints each matched signature with the hosts it matched on,
+// the most widespread first.
+func printRollUp(s summary) {
+	names := make([]string, 0, len(s.sigHosts))
+	for name := range s.sigHosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(s.sigHosts[names[i]]) > len(s.sigHosts[names[j]])
+	})
+	if len(names) == 0 {
+		fmt.Fprintln(stdout, style("No composite service signatures recognised.", yellow, false, false))
+	}
+	for _, name := range names {
+		hosts := make(map[string]*nsight.Host, len(s.sigHosts[name]))
+		for h := range s.sigHosts[name] {
+			hosts[h] = nil
+		}
+		sorted := nsight.SortedHosts(hosts)
+		fmt.Fprintf(stdout, "%s %s found on %d host(s): %s\n", style("▶", cyan, true, false), style(name, "", true, false),
+			len(sorted), strings.Join(abbreviateHosts(sorted), ", "))
+	}
+	fmt.Fprintln(stdout)
+}
 
-func parseNmap(path string) (map[int]struct{}, error) {
-	f, err := os.Open(path)
+// abbreviateHosts shortens each IPv4 address that shares its /24 with the one
+// before it to its last octet, so "10.0.0.5, 10.0.0.9" reads "10.0.0.5, .9".
+func abbreviateHosts(hosts []string) []string {
+	out := make([]string, len(hosts))
+	prev := ""
+	for i, h := range hosts {
+		out[i] = h
+		addr, err := netip.ParseAddr(h)
+		if err != nil || !addr.Is4() {
+			prev = ""
+			continue
+		}
+		prefix := h[:strings.LastIndex(h, ".")]
+		if prefix == prev {
+			out[i] = h[len(prefix):]
+		}
+		prev = prefix
+	}
+	return out
+}
+
+// validateSignatures implements --validate-signatures and returns the exit
+// code: 0 when the file is clean, 1 when it has problems. Unknown fields are
+// warned about but don't fail the file, since loading ignores them.
+func validateSignatures(path string) int {
+	checked, problems, ignored, err := nsight.Lint(path)
 	if err != nil {
-		return nil, err
+		errorf("cannot load signatures: %v", err)
+		return exitError
 	}
-	defer f.Close()
+	for _, p := range problems {
+		fmt.Fprintf(stdout, "%s: %s\n", path, p)
+	}
+	for _, p := range ignored {
+		fmt.Fprintf(stdout, "%s: %s %s\n", path, style("warning:", yellow, true, false), p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintln(stdout, style(fmt.Sprintf("FAIL: %d problem(s) in %d signature(s)", len(problems), checked), red, true, false))
+		return exitNoMatch
+	}
+	line := fmt.Sprintf("PASS: %d signature(s) checked", checked)
+	if len(ignored) > 0 {
+		line += fmt.Sprintf(", %d warning(s)", len(ignored))
+	}
+	fmt.Fprintln(stdout, style(line, green, true, false))
+	return exitMatch
+}
 
-	re := regexp.MustCompile(`^(\d+)/tcp\s+open`)
-	ports := make(map[int]struct{})
-	s := bufio.NewScanner(f)
-	for s.Scan() {
-		line := strings.TrimSpace(s.Text())
-		if m := re.FindStringSubmatch(line); m != nil {
-			if p, _ := strconv.Atoi(m[1]); p > 0 {
-				ports[p] = struct{}{}
+// runDiff implements --diff and returns the exit code: 0 when the scans
+// differ, 1 when they don't. Under --since it takes any number of scans and
+// compares the two pickSince chooses.
+func runDiff(paths []string, sigs []nsight.Signature, merge bool, minConfidence float64) int {
+	switch {
+	case since.IsZero() && len(paths) != 2:
+		errorf("--diff needs exactly two scan files: old and new")
+		return exitError
+	case len(paths) < 2:
+		errorf("--diff --since needs at least two scan files")
+		return exitError
+	}
+	scans := make([]map[string]*nsight.Host, len(paths))
+	for i, path := range paths {
+		hosts, skipped, err := parseNmap(path)
+		for _, line := range skipped {
+			warnf("%s: ignoring %q", path, line)
+		}
+		if err != nil {
+			errorf("cannot parse %s: %v", path, err)
+			return exitError
+		}
+		if merge {
+			merged := nsight.NewHost()
+			for _, h := range hosts {
+				merged.Merge(h)
 			}
+			hosts = map[string]*nsight.Host{"": merged}
+		}
+		scans[i] = hosts
+	}
+	old, cur := 0, 1
+	if !since.IsZero() {
+		var err error
+		if old, cur, err = pickSince(paths, scans); err != nil {
+			errorf("%v", err)
+			return exitError
+		}
+		fmt.Fprintln(stdout, style(fmt.Sprintf("Comparing %s (%s) with %s (%s)",
+			paths[old], scanStarted(scans[old]).Format(sinceShown),
+			paths[cur], scanStarted(scans[cur]).Format(sinceShown)), "", false, true))
+		fmt.Fprintln(stdout)
+	} else if a, b := scanStarted(scans[0]), scanStarted(scans[1]); !a.IsZero() && b.Before(a) && !b.IsZero() {
+		warnf("%s was scanned before %s; comparing them in that order", paths[1], paths[0])
+		old, cur = 1, 0
+	}
+	if len(onlyHosts) > 0 && len(scans[old])+len(scans[cur]) == 0 {
+		errorf("--host %s matched no hosts", onlyHosts.String())
+		return exitError
+	}
+	if diffScans(scans[old], scans[cur], sigs, minConfidence) {
+		return exitMatch
+	}
+	return exitNoMatch
+}
+
+// scanStarted returns the earliest start time among hosts, or the zero time
+// if none recorded one.
+func scanStarted(hosts map[string]*nsight.Host) time.Time {
+	var first time.Time
+	for _, h := range hosts {
+		if !h.Started.IsZero() && (first.IsZero() || h.Started.Before(first)) {
+			first = h.Started
 		}
 	}
-	return ports, s.Err()
+	return first
 }
 
-func knownSignatures() []Signature {
-	return []Signature{
-		{Name: "SMB / NetBIOS file share", Required: []int{139, 445}},
-		{Name: "Active Directory Domain Controller", Required: []int{53, 88, 389, 445, 464}, Optional: []int{636, 3268, 3269, 5985, 9389}},
-		{Name: "Windows RPC services (EPM + dynamic RPC)", Required: []int{135}},
-		{Name: "Windows Remote Management / WinRM", Required: []int{5985}, Optional: []int{5986}},
-		{Name: "NFS server (rpcbind + nfsd)", Required: []int{111, 2049}, Optional: []int{20048, 4045, 4049}},
-		{Name: "FTP", Required: []int{21}, Optional: []int{20}},
-		{Name: "Mail stack (SMTP + POP)", Required: []int{25, 110}},
-		{Name: "Mail stack (SMTP + IMAP)", Required: []int{25, 143}},
-		{Name: "Mail stack (SMTP + IMAPS)", Required: []int{25, 993}},
-		{Name: "SIP / VoIP server", Required: []int{5060}},
-		{Name: "Network printer (JetDirect + LPD)", Required: []int{515, 9100}},
-		{Name: "Oracle Database", Required: []int{1521}, Optional: []int{1522, 2483, 2484}},
-		{Name: "MySQL / MariaDB", Required: []int{3306}, Optional: []int{33060}},
-		{Name: "Microsoft SQL Server", Required: []int{1433}, Optional: []int{}},
-		{Name: "PostgreSQL", Required: []int{5432}, Optional: []int{5433}},
-		{Name: "IBM Db2 Database", Required: []int{50000}, Optional: []int{50001, 50050}}, // this should be all ports from 50001-50050 but cbf
-		{Name: "SAP NetWeaver Application Server", Required: []int{3200, 3300}, Optional: []int{3600, 8000, 8001, 3299}},
-		{Name: "Elasticsearch", Required: []int{9200}, Optional: []int{9300}},
-		{Name: "Splunk Enterprise", Required: []int{8000, 8089, 9997}, Optional: []int{8088}}, // should also have UDP/514 as optional
-		{Name: "VMware vCenter Server", Required: []int{443}, Optional: []int{5480, 902}},
-		{Name: "MongoDB Database", Required: []int{27017}, Optional: []int{27018, 27019}},
-		{Name: "Redis", Required: []int{6379}, Optional: []int{26379, 16379}},
-		{Name: "Apache Cassandra", Required: []int{9042}, Optional: []int{7000, 9160}},
+// matchHost runs sigs and any registered detectors against h, drops matches
+// below minConfidence or --min-severity and, unless --show-all is set, those
+// a stronger match supersedes. The most severe matches come first.
+func matchHost(h *nsight.Host, sigs []nsight.Signature, minConfidence float64) []nsight.Result {
+	matches := atLeast(nsight.Detect(h, detectors(sigs)...), minConfidence)
+	kept := matches[:0]
+	for _, m := range matches {
+		if m.Severity >= minSeverity {
+			kept = append(kept, m)
+		}
+	}
+	matches = kept
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Severity > matches[j].Severity
+	})
+	if !showAll {
+		matches = nsight.DropSuperseded(matches, sigs)
+	}
+	if bestOnly && len(matches) > 1 {
+		matches = []nsight.Result{best(matches)}
 	}
+	return matches
 }
 
-func hasAll(set map[int]struct{}, req []int) bool {
-	for _, p := range req {
-		if _, ok := set[p]; !ok {
-			return false
+// best picks the highest-confidence match, preferring the one with more
+// required ports on a tie and the earlier one after that.
+func best(matches []nsight.Result) nsight.Result {
+	top := matches[0]
+	for _, m := range matches[1:] {
+		if m.Confidence > top.Confidence ||
+			m.Confidence == top.Confidence && len(m.RequiredPresent) > len(top.RequiredPresent) {
+			top = m
 		}
 	}
-	return true
+	return top
 }
 
-func presentOptional(set map[int]struct{}, opt []int) []int {
-	var present []int
-	for _, p := range opt {
-		if _, ok := set[p]; ok {
-			present = append(present, p)
+// detectors returns sigs as a detector followed by the registered ones.
+func detectors(sigs []nsight.Signature) []nsight.Detector {
+	return append([]nsight.Detector{nsight.SignatureDetector(sigs)}, nsight.RegisteredDetectors()...)
+}
+
+// atLeast drops matches whose confidence is below min.
+func atLeast(matches []nsight.Result, min float64) []nsight.Result {
+	out := matches[:0]
+	for _, m := range matches {
+		if m.Confidence >= min {
+			out = append(out, m)
 		}
 	}
-	return present
+	return out
+}
+
+// sigFilter is the set of flags that narrow down which signatures run.
+type sigFilter struct {
+	only, exclude string // name substrings
+	category      string
+	match, not    *regexp.Regexp // name patterns, nil when unset
+}
+
+// nameRegexp compiles a --match or --not pattern to ignore case. An empty
+// pattern gives nil.
+func nameRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	// Compile the pattern as given first so errors quote what the user typed.
+	if _, err := regexp.Compile(expr); err != nil {
+		return nil, err
+	}
+	return regexp.MustCompile("(?i)" + expr), nil
 }
 
-func diff(all, subset []int) []int {
-	m := make(map[int]struct{}, len(subset))
-	for _, p := range subset {
-		m[p] = struct{}{}
+// filterSignatures keeps signatures whose name contains f.only and matches
+// f.match, neither contains f.exclude nor matches f.not, and whose category
+// is f.category, skipping any test that is unset and ignoring case
+// throughout.
+func filterSignatures(sigs []nsight.Signature, f sigFilter) []nsight.Signature {
+	only, exclude := strings.ToLower(f.only), strings.ToLower(f.exclude)
+	var out []nsight.Signature
+	for _, sig := range sigs {
+		if f.category != "" && !strings.EqualFold(sig.Category, f.category) {
+			continue
+		}
+		name := strings.ToLower(sig.Name)
+		if only != "" && !strings.Contains(name, only) {
+			continue
+		}
+		if exclude != "" && strings.Contains(name, exclude) {
+			continue
+		}
+		if f.match != nil && !f.match.MatchString(sig.Name) {
+			continue
+		}
+		if f.not != nil && f.not.MatchString(sig.Name) {
+			continue
+		}
+		out = append(out, sig)
 	}
-	var out []int
-	for _, p := range all {
-		if _, ok := m[p]; !ok {
+	return out
+}
+
+// listSignatures prints sigs as an aligned table. Padding is applied before
+// styling so escape codes don't skew the columns.
+func listSignatures(sigs []nsight.Signature) {
+	nameWidth, catWidth, reqWidth := len("SIGNATURE"), len("CATEGORY"), len("REQUIRED")
+	for _, sig := range sigs {
+		nameWidth = max(nameWidth, len(sig.Name))
+		catWidth = max(catWidth, len(sig.Category))
+		reqWidth = max(reqWidth, len(requiredList(sig)))
+	}
+	fmt.Fprintf(stdout, "%s  %-*s  %-*s  %s\n", style(fmt.Sprintf("%-*s", nameWidth, "SIGNATURE"), "", true, false),
+		catWidth, "CATEGORY", reqWidth, "REQUIRED", "OPTIONAL")
+	for _, sig := range sigs {
+		fmt.Fprintf(stdout, "%s  %-*s  %-*s  %s\n", style(fmt.Sprintf("%-*s", nameWidth, sig.Name), cyan, true, false),
+			catWidth, sig.Category, reqWidth, requiredList(sig), portList(sig.Optional))
+	}
+}
+
+// requiredList renders what a signature needs: its required ports,
+// "any of (...)" for each AnyOf group and any banner text it looks for.
+func requiredList(sig nsight.Signature) string {
+	parts := []string{}
+	if len(sig.Required) > 0 {
+		parts = append(parts, portList(sig.Required))
+	}
+	for _, g := range sig.AnyOf {
+		parts = append(parts, anyOf(g.Min)+" ("+portList(g.Ports)+")")
+	}
+	var bannerPorts []nsight.Port
+	for p := range sig.BannerContains {
+		bannerPorts = append(bannerPorts, p)
+	}
+	nsight.SortPorts(bannerPorts)
+	for _, p := range bannerPorts {
+		parts = append(parts, fmt.Sprintf("%s banner %q", p, sig.BannerContains[p]))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// portList is joinPorts without styling, for tables that need stable widths.
+func portList(ports []nsight.Port) string {
+	sorted := append([]nsight.Port{}, ports...)
+	nsight.SortPorts(sorted)
+	return strings.Join(portRuns(sorted), ", ")
+}
+
+// weakConfidence is the confidence below which a text report lists a match
+// by name only, unless --verbose is given.
+const weakConfidence = 0.5
+
+// weak reports whether m is listed by name only: it scored below
+// weakConfidence, is less than High severity and doesn't trip gate.
+func weak(m nsight.Result, gate failGate) bool {
+	return m.Confidence < weakConfidence && m.Severity < nsight.High && !gate.trips(m)
+}
+
+// report prints matches for openPorts in the human-readable format, or just
+// their names under --quiet (nothing under --count-only). Weak matches are
+// listed by name on one line, and unexplained ports left out, unless
+// --verbose is given.
+func report(h *nsight.Host, matches []nsight.Result, misses []nsight.NearMiss, unexplained []nsight.Port, gate failGate) {
+	if countOnly {
+		return
+	}
+	if quiet {
+		for _, m := range matches {
+			fmt.Fprintln(stdout, m.Signature)
+		}
+		return
+	}
+	if h == nil || len(h.Ports) == 0 {
+		fmt.Fprintln(stdout, style("No open ports found.", yellow, false, false))
+		fmt.Fprintf(stdout, "\n")
+		return
+	}
+
+	strong, terse := matches, []string(nil)
+	if !verbose {
+		strong = nil
+		for _, m := range matches {
+			if weak(m, gate) {
+				terse = append(terse, fmt.Sprintf("%s (%.2f)", m.Signature, m.Confidence))
+			} else {
+				strong = append(strong, m)
+			}
+		}
+	}
+	for _, group := range groupByCategory(strong) {
+		fmt.Fprintln(stdout, style("["+group.name+"]", yellow, true, false))
+		for _, m := range group.matches {
+			printMatch(m)
+		}
+	}
+	if len(terse) > 0 {
+		fmt.Fprintln(stdout, style(fmt.Sprintf("%d weak match(es) below confidence %.2f, shown with --verbose: %s",
+			len(terse), weakConfidence, strings.Join(terse, ", ")), "", false, true))
+	}
+
+	if len(matches) == 0 {
+		fmt.Fprintln(stdout, style("No composite service signatures recognised.", yellow, false, false))
+	}
+
+	if verbose && len(unexplained) > 0 {
+		fmt.Fprintln(stdout, style(fmt.Sprintf("%d of %d open port(s) unexplained: %s", len(unexplained), len(h.Ports), portList(unexplained)), "", false, true))
+	}
+
+	for _, n := range misses {
+		fmt.Fprintf(stdout, "%s %s: %d/%d required present, missing %s\n",
+			style("▷", yellow, true, false),
+			style("Near miss "+n.Signature, "", true, false),
+			len(n.RequiredPresent), len(n.RequiredPresent)+len(n.RequiredMissing),
+			joinPorts(n.RequiredMissing, "", false, true))
+	}
+
+	fmt.Fprintf(stdout, "\n")
+}
+
+// printMatch prints one match as a single line, plus banners if requested.
+// Missing optional ports are only listed under --verbose.
+func printMatch(m nsight.Result) {
+	header := style("▶", severityColour(m.Severity), true, false)
+	service := style("Possible "+m.Signature+" detected", confidenceColour(m.Confidence), true, false)
+
+	// A port listed in several roles is shown once, in its strongest.
+	shown := nsight.NewPortSet(m.RequiredPresent)
+	for _, g := range m.AnyOf {
+		for _, p := range g.Present {
+			shown.Add(p)
+		}
+	}
+	var clauses []string
+	if len(m.RequiredPresent) > 0 {
+		clauses = append(clauses, fmt.Sprintf("Required ports %s are present",
+			joinPorts(m.RequiredPresent, requiredColour, true, false)))
+	}
+	for _, g := range m.AnyOf {
+		clauses = append(clauses, fmt.Sprintf("%s: %s present", groupLabel(g),
+			joinPorts(g.Present, requiredColour, true, false)))
+	}
+	if optional := unshown(m.OptionalPresent, shown); len(optional) > 0 {
+		list := joinPorts(optional, optionalColour, true, false)
+		if sig, ok := weighted[m.Signature]; ok {
+			list = joinByWeight(optional, sig, optionalColour, true, false)
+		}
+		clauses = append(clauses, fmt.Sprintf("optional ports %s are also present", list))
+	}
+	if missing := unshown(m.OptionalMissing, shown); verbose && len(missing) > 0 {
+		clauses = append(clauses, fmt.Sprintf("optional ports %s are missing",
+			joinPorts(missing, "", false, true)))
+	}
+	line := header + " " + service
+	if len(clauses) > 0 {
+		line += ": " + strings.Join(clauses, ", ")
+	}
+	fmt.Fprintln(stdout, line, style(fmt.Sprintf("(%s, confidence %.2f)", m.Severity, m.Confidence), "", false, true))
+	if showBanners {
+		printBanners(m)
+	}
+}
+
+// severityColour picks the colour of a match's glyph.
+func severityColour(s nsight.Severity) string {
+	switch {
+	case s >= nsight.Critical:
+		return magenta
+	case s >= nsight.High:
+		return red
+	case s >= nsight.Medium:
+		return yellow
+	case s >= nsight.Low:
+		return cyan
+	}
+	return green
+}
+
+// confidenceColour grades a match's name from red through yellow to green as
+// its confidence rises.
+func confidenceColour(c float64) string {
+	switch {
+	case c >= 0.75:
+		return green
+	case c >= weakConfidence:
+		return yellow
+	}
+	return red
+}
+
+// unshown returns the ports not already in shown.
+func unshown(ports []nsight.Port, shown nsight.PortSet) []nsight.Port {
+	var out []nsight.Port
+	for _, p := range ports {
+		if !shown.Has(p) {
 			out = append(out, p)
 		}
 	}
 	return out
 }
+
+// printLegend explains the port colours used by the text report. Missing
+// ports only appear under --verbose or --show-near-misses.
+func printLegend() {
+	legend := style("Ports:", "", false, true) + " " +
+		style("required", requiredColour, true, false) + "  " +
+		style("optional (open)", optionalColour, true, false)
+	if verbose || showNearMisses {
+		legend += "  " + style("missing", "", false, true)
+	}
+	fmt.Fprintf(stdout, "%s\n\n", legend)
+}
+
+// groupLabel describes an AnyOf group, e.g. "mail access (any of 110, 143)".
+func groupLabel(g nsight.GroupMatch) string {
+	all := append(append([]nsight.Port{}, g.Present...), g.Missing...)
+	label := anyOf(g.Min) + " " + portList(all)
+	if g.Name != "" {
+		label = g.Name + " (" + label + ")"
+	}
+	return label
+}
+
+// anyOf phrases a group minimum: "any of" or "any 2 of".
+func anyOf(min int) string {
+	if min > 1 {
+		return fmt.Sprintf("any %d of", min)
+	}
+	return "any of"
+}
+
+type categoryGroup struct {
+	name    string
+	matches []nsight.Result
+}
+
+// groupByCategory buckets matches by signature category, alphabetically,
+// with uncategorised matches last under "Other". Order within a bucket is
+// preserved.
+func groupByCategory(matches []nsight.Result) []categoryGroup {
+	index := make(map[string]int)
+	var groups []categoryGroup
+	for _, m := range matches {
+		name := m.Category
+		if name == "" {
+			name = "Other"
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, categoryGroup{name: name})
+		}
+		groups[i].matches = append(groups[i].matches, m)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if (groups[i].name == "Other") != (groups[j].name == "Other") {
+			return groups[j].name == "Other"
+		}
+		return groups[i].name < groups[j].name
+	})
+	return groups
+}
+
+// unexplained returns the open ports of h that no signature or detector
+// accounts for. Every match counts, including ones hidden by
+// --min-confidence, --min-severity or superseding, since the port was still
+// recognised.
+func unexplained(h *nsight.Host, sigs []nsight.Signature) []nsight.Port {
+	return nsight.Unexplained(h, nsight.Detect(h, detectors(sigs)...))
+}
+
+// nearMisses returns the near misses for h when --show-near-misses is set.
+func nearMisses(h *nsight.Host, sigs []nsight.Signature) []nsight.NearMiss {
+	if !showNearMisses && !verbose {
+		return nil
+	}
+	return nsight.NearMisses(h, sigs, nearMissThreshold)
+}
+
+// printBanners lists the -sV service text nmap recorded for m's ports.
+func printBanners(m nsight.Result) {
+	ports := make([]nsight.Port, 0, len(m.Banners))
+	for p := range m.Banners {
+		ports = append(ports, p)
+	}
+	nsight.SortPorts(ports)
+	for _, p := range ports {
+		fmt.Fprintf(stdout, "    %s %s\n", style(p.String()+":", "", false, true), m.Banners[p])
+	}
+}
+
+// --- helpers -------------------------------------------------------------
+
+// stdinIsTTY reports whether stdin is an interactive terminal rather than a pipe.
+func stdinIsTTY() bool {
+	return isTerminal(os.Stdin)
+}
+
+// isTerminal reports whether f is a character device, such as a terminal.
+// A file that can't be stat'ed counts as one; a writer that isn't a file,
+// such as a test's buffer, doesn't.
+func isTerminal(f any) bool {
+	file, ok := f.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := file.Stat()
+	return err != nil || fi.Mode()&os.ModeCharDevice != 0
+}
+
+// parseNmap reads the scan at path, where "-" means stdin. Under --strict it
+// also returns the port lines that were not counted as open, for the caller
+// to warn about.
+func parseNmap(path string) (map[string]*nsight.Host, []string, error) {
+	var skipped []string
+	opts := nsight.ParseOptions{IncludeFiltered: includeFiltered}
+	if strict {
+		opts.Strict = true
+		opts.Skipped = func(line string) {
+			skipped = append(skipped, line)
+		}
+	}
+	hosts, err := parseCached(path, opts, &skipped)
+	return ignoredPorts.apply(onlyHosts.apply(hosts)), skipped, err
+}
+
+// readScan parses path, which may also be "-" for stdin, an http(s) URL or
+// the name of a --scan or --ports input.
+func readScan(path string, opts nsight.ParseOptions) (map[string]*nsight.Host, error) {
+	if path == "-" {
+		return nsight.ParseNmapReaderWith(os.Stdin, opts)
+	}
+	if xml, ok := liveScans[path]; ok {
+		return nsight.ParseNmapReaderWith(bytes.NewReader(xml), opts)
+	}
+	if h, ok := portLists[path]; ok {
+		return map[string]*nsight.Host{"": h}, nil
+	}
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return fetchScan(path, opts)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return nsight.ParseNmapReaderWith(f, opts)
+}
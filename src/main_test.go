@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI calls run with a fresh pair of buffers.
+func runCLI(t *testing.T, args ...string) (code int, out, errOut string) {
+	t.Helper()
+	var o, e bytes.Buffer
+	code = run(args, &o, &e)
+	return code, o.String(), e.String()
+}
+
+func TestRunTwice(t *testing.T) {
+	_, first, _ := runCLI(t, "testdata/dc.nmap")
+	_, best, _ := runCLI(t, "--mode", "best", "--host", "10.0.0.9", "testdata/dc.nmap")
+	if best == first {
+		t.Fatal("--mode best --host made no difference")
+	}
+	code, again, errOut := runCLI(t, "testdata/dc.nmap")
+	if code != exitMatch || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	if again != first {
+		t.Errorf("a second run kept state from the one before:\nfirst:\n%s\nagain:\n%s", first, again)
+	}
+}
+
+func TestIgnorePortProtocol(t *testing.T) {
+	_, all, _ := runCLI(t, "--quiet", "--show-all", "testdata/dc.nmap")
+	if !strings.Contains(all, "Active Directory Domain Controller") {
+		t.Fatalf("want the DC matched without --ignore-port, got %q", all)
+	}
+	for _, spec := range []string{"445", "445/tcp", "445/TCP"} {
+		_, out, _ := runCLI(t, "--quiet", "--show-all", "--ignore-port", spec, "testdata/dc.nmap")
+		if strings.Contains(out, "Active Directory Domain Controller") || strings.Contains(out, "SMB") {
+			t.Errorf("--ignore-port %s left 445 open: %q", spec, out)
+		}
+	}
+	code, _, errOut := runCLI(t, "--ignore-port", "445/sctp", "testdata/dc.nmap")
+	if code != exitError || !strings.Contains(errOut, `unknown protocol "sctp"`) {
+		t.Errorf("--ignore-port 445/sctp: exit %d, stderr %q", code, errOut)
+	}
+}
+
+func TestWeakMatchesTerseByDefault(t *testing.T) {
+	// Lab database is Low and scores 0.25 on 10.0.0.9; Redis is High and
+	// scores 0.33, so it is always shown in full.
+	sigs := filepath.Join(t.TempDir(), "sigs.json")
+	if err := os.WriteFile(sigs, []byte(`[{"name": "Lab database", "severity": "low", "required": [5432], "optional": [5433, 5434, 5435]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	terse := "1 weak match(es) below confidence 0.50, shown with --verbose: Lab database (0.25)"
+	tests := []struct {
+		name      string
+		args      []string
+		want, not []string
+	}{
+		{"default", nil, []string{terse, "Possible Redis detected", "Possible PostgreSQL detected"}, []string{"Possible Lab database detected"}},
+		{"verbose", []string{"--verbose"}, []string{"Possible Lab database detected", "Possible Redis detected"}, []string{"weak match"}},
+		{"fail-on", []string{"--fail-on", "lab"}, []string{"Possible Lab database detected"}, []string{"weak match"}},
+		{"fail-on-severity", []string{"--fail-on-severity", "low"}, []string{"Possible Lab database detected"}, []string{"weak match"}},
+		{"fail-on another", []string{"--fail-on", "redis"}, []string{terse}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(append([]string{"--signatures", sigs, "--host", "10.0.0.9"}, tt.args...), "testdata/dc.nmap")
+			_, out, _ := runCLI(t, args...)
+			for _, s := range tt.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("want %q in\n%s", s, out)
+				}
+			}
+			for _, s := range tt.not {
+				if strings.Contains(out, s) {
+					t.Errorf("want no %q in\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSignaturesUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sigs.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "A", "required": [80], "colour": "red"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, out, _ := runCLI(t, "--validate-signatures", path)
+	if code != exitMatch || !strings.Contains(out, `warning: signature 1 ("A"): unknown field "colour"`) ||
+		!strings.Contains(out, "PASS: 1 signature(s) checked, 1 warning(s)") {
+		t.Errorf("exit %d:\n%s", code, out)
+	}
+}
+
+func TestSummaryCountsHostsOnce(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{"one file", []string{"testdata/dc.nmap"}, "on 2 hosts"},
+		// 10.0.0.5 is in both files.
+		{"two files", []string{"testdata/dc.nmap", "testdata/ssh.nmap"}, "on 2 hosts"},
+		{"three files", []string{"testdata/dc.nmap", "testdata/ssh.nmap", "testdata/reason.nmap"}, "on 3 hosts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out, _ := runCLI(t, append([]string{"--summary"}, tt.files...)...)
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("want %q in\n%s", tt.want, out)
+			}
+		})
+	}
+}
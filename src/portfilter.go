@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// portFilter holds the --ignore-port values: ports removed from every host
+// before matching, for noise such as a reverse proxy's 443.
+type portFilter []nsight.Port
+
+func (f *portFilter) String() string { return portList(*f) }
+
+// Set accepts anything a signature's port spec does: "443", "161/udp",
+// "8000-8100" or a comma-separated mix.
+func (f *portFilter) Set(v string) error {
+	ports, err := nsight.ParsePortSpec(v)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, ports...)
+	return nil
+}
+
+// apply removes the filter's ports from hosts, dropping any host left with
+// no open ports, as if nmap had never reported them.
+func (f portFilter) apply(hosts map[string]*nsight.Host) map[string]*nsight.Host {
+	if len(f) == 0 {
+		return hosts
+	}
+	for host, h := range hosts {
+		for _, p := range f {
+			h.Remove(p)
+		}
+		if len(h.Ports) == 0 {
+			delete(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// scan is one input file after a worker has parsed and matched it.
+type scan struct {
+	hosts   map[string]*nsight.Host
+	matches map[string][]nsight.Result // by host; empty when match is nil
+	skipped []string                   // port lines --strict did not count
+	err     error
+}
+
+// scanAll parses paths on up to jobs workers and, if match is set, runs it on
+// every host. Results are returned in input order however the workers
+// finish, so output stays deterministic. On a terminal, a progress line on
+// stderr counts the files done until the last one finishes.
+func scanAll(paths []string, jobs int, match func(*nsight.Host) []nsight.Result) []scan {
+	type done struct {
+		i int
+		s scan
+	}
+	todo := make(chan int)
+	results := make(chan done)
+	var wg sync.WaitGroup
+	for w := 0; w < max(1, min(jobs, len(paths))); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range todo {
+				results <- done{i, scanFile(paths[i], match)}
+			}
+		}()
+	}
+	go func() {
+		for i := range paths {
+			todo <- i
+		}
+		close(todo)
+		wg.Wait()
+		close(results)
+	}()
+	out := make([]scan, len(paths))
+	progress := len(paths) > 1 && !quiet && isTerminal(stderr)
+	finished := 0
+	for d := range results {
+		out[d.i] = d.s
+		if finished++; progress {
+			fmt.Fprintf(stderr, "\rnsight: processed %d/%d files", finished, len(paths))
+		}
+	}
+	if progress {
+		fmt.Fprint(stderr, "\r\033[K")
+	}
+	return out
+}
+
+// anyMatches reports whether any host in s matched.
+func anyMatches(s scan) bool {
+	for _, m := range s.matches {
+		if len(m) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// scanFile is the per-file work done by a scanAll worker.
+func scanFile(path string, match func(*nsight.Host) []nsight.Result) scan {
+	var s scan
+	s.hosts, s.skipped, s.err = parseNmap(path)
+	s.hosts = keepSince(s.hosts)
+	if s.err != nil || match == nil {
+		return s
+	}
+	s.matches = make(map[string][]nsight.Result, len(s.hosts))
+	for host, h := range s.hosts {
+		s.matches[host] = approved.matches(host, match(h))
+	}
+	return s
+}
+
+// scanExtensions are the file names --dir picks up, each optionally gzipped.
+var scanExtensions = []string{".nmap", ".xml", ".gnmap"}
+
+// findScans walks dir for scan files and returns them in lexical order.
+func findScans(dir string) ([]string, error) {
+	var out []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := strings.TrimSuffix(strings.ToLower(d.Name()), ".gz")
+		for _, ext := range scanExtensions {
+			if strings.HasSuffix(name, ext) {
+				out = append(out, path)
+				break
+			}
+		}
+		return nil
+	})
+	sort.Strings(out)
+	return out, err
+}
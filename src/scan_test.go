@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// BenchmarkScanAll compares one worker with several over many files.
+func BenchmarkScanAll(b *testing.B) {
+	dir := b.TempDir()
+	var paths []string
+	for i := 0; i < 200; i++ {
+		var sb strings.Builder
+		for h := 0; h < 20; h++ {
+			fmt.Fprintf(&sb, "Nmap scan report for 10.%d.%d.1\nPORT STATE SERVICE\n", i, h)
+			for _, p := range []int{22, 53, 80, 88, 135, 139, 389, 443, 445, 464, 636, 3268, 5985} {
+				fmt.Fprintf(&sb, "%d/tcp open  unknown\n", p)
+			}
+		}
+		path := filepath.Join(dir, fmt.Sprintf("scan%d.nmap", i))
+		if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+			b.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	sigs := nsight.Signatures()
+	match := func(h *nsight.Host) []nsight.Result { return matchHost(h, sigs, 0) }
+	defer func(q bool) { quiet = q }(quiet)
+	quiet = true
+	for _, jobs := range []int{1, max(4, runtime.GOMAXPROCS(0))} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scanAll(paths, jobs, match)
+			}
+		})
+	}
+}
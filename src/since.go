@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// sinceLayouts are the --since formats besides RFC 3339. Like nmap's own
+// timestamps they are read as local time.
+var sinceLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// parseSince reads a --since value such as "2026-10-01", "2026-10-01 09:00"
+// or "2026-10-01T09:00:00Z".
+func parseSince(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date such as 2026-10-01, 2026-10-01 09:00 or an RFC 3339 time", v)
+}
+
+// keepSince drops the hosts scanned before --since. Hosts whose scan didn't
+// record when it ran are kept.
+func keepSince(hosts map[string]*nsight.Host) map[string]*nsight.Host {
+	if since.IsZero() {
+		return hosts
+	}
+	for host, h := range hosts {
+		if !h.Started.IsZero() && h.Started.Before(since) {
+			delete(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// pickSince chooses the two scans --diff --since compares: the last one
+// started by --since, as the state at that time, and the newest. Every scan
+// must record when it ran.
+func pickSince(paths []string, scans []map[string]*nsight.Host) (old, cur int, err error) {
+	order := make([]int, len(scans))
+	starts := make([]time.Time, len(scans))
+	for i, hosts := range scans {
+		if starts[i] = scanStarted(hosts); starts[i].IsZero() {
+			return 0, 0, fmt.Errorf("--since needs scans that record when they ran, and %s doesn't", paths[i])
+		}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return starts[order[a]].Before(starts[order[b]]) })
+	old = -1
+	for _, i := range order {
+		if !starts[i].After(since) {
+			old = i
+		}
+	}
+	if old < 0 {
+		return 0, 0, fmt.Errorf("no scan started by %s; the first was %s", since.Format(sinceShown), starts[order[0]].Format(sinceShown))
+	}
+	return old, order[len(order)-1], nil
+}
+
+// sinceShown is how scan times are printed alongside --since.
+const sinceShown = "2006-01-02 15:04"
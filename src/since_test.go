@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSinceReport(t *testing.T) {
+	tests := []struct {
+		since string
+		want  int
+	}{
+		{"2026-10-13", exitMatch},
+		{"2026-10-13 10:00", exitMatch},
+		{"2026-10-14", exitError},
+		{"2026-10-13 10:00:01", exitError},
+		{"next week", exitError},
+	}
+	for _, tt := range tests {
+		code, _, errOut := runCLI(t, "--since", tt.since, "testdata/dc.nmap")
+		if code != tt.want {
+			t.Errorf("--since %q: exit %d, want %d (%s)", tt.since, code, tt.want, errOut)
+		}
+	}
+}
+
+func TestDiffSince(t *testing.T) {
+	dir := t.TempDir()
+	scan := func(name, started string, ports ...string) string {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "# Nmap 7.94 scan initiated %s as: nmap -oN %s 10.0.0.5\n", started, name)
+		sb.WriteString("Nmap scan report for 10.0.0.5\nPORT     STATE SERVICE\n")
+		for _, p := range ports {
+			fmt.Fprintf(&sb, "%s open  unknown\n", p)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// Given out of order; --since sorts them by when they ran.
+	latest := scan("oct13.nmap", "Tue Oct 13 09:00:00 2026", "22/tcp", "445/tcp", "6379/tcp")
+	first := scan("oct01.nmap", "Thu Oct  1 09:00:00 2026", "22/tcp")
+	middle := scan("oct06.nmap", "Tue Oct  6 09:00:00 2026", "22/tcp", "445/tcp")
+	files := []string{latest, first, middle}
+
+	code, out, errOut := runCLI(t, append([]string{"--diff", "--since", "2026-10-07"}, files...)...)
+	if code != exitMatch {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	for _, want := range []string{"Comparing " + middle + " (2026-10-06 09:00) with " + latest, "+ Possible Redis detected", "ports opened: 6379\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "445") {
+		t.Errorf("445 was already open on 2026-10-07:\n%s", out)
+	}
+
+	if code, _, errOut = runCLI(t, append([]string{"--diff", "--since", "2026-09-01"}, files...)...); code != exitError || !strings.Contains(errOut, "no scan started by") {
+		t.Errorf("--since before every scan: exit %d, stderr %q", code, errOut)
+	}
+	if code, _, _ = runCLI(t, "--diff", latest, first, middle); code != exitError {
+		t.Errorf("three scans without --since: exit %d, want %d", code, exitError)
+	}
+	if code, _, errOut = runCLI(t, "--diff", "--since", "2026-10-07", latest, "testdata/ssh.nmap"); code != exitError || !strings.Contains(errOut, "record when they ran") {
+		t.Errorf("undated scan: exit %d, stderr %q", code, errOut)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	for _, v := range []string{"2026-10-01", "2026-10-01 09:00", "2026-10-01 09:00:30", "2026-10-01T09:00:00Z", "2026-10-01T09:00:00+02:00"} {
+		if _, err := parseSince(v); err != nil {
+			t.Errorf("parseSince(%q): %v", v, err)
+		}
+	}
+	for _, v := range []string{"", "yesterday", "01/10/2026", "2026-13-01"} {
+		if _, err := parseSince(v); err == nil {
+			t.Errorf("parseSince(%q) accepted", v)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// namedTemplates are the --template values that need no Go template syntax.
+var namedTemplates = map[string]string{
+	"syslog":  `nsight: host={{.Host}} signature="{{.Signature}}" severity={{.Severity}} confidence={{printf "%.2f" .Confidence}}`,
+	"oneline": `{{with .File}}{{.}} {{end}}{{.Host}}: {{.Signature}} [{{ports .RequiredPresent .AnyOfPresent}}]`,
+}
+
+// parseFindingTemplate builds the --template for one finding from a named
+// template or Go template text. Each rendering ends in a newline, added if
+// the text doesn't supply one.
+func parseFindingTemplate(text string) (*template.Template, error) {
+	if named, ok := namedTemplates[text]; ok {
+		text = named
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return template.New("finding").Funcs(template.FuncMap{
+		"ports": joinPortLists,
+		"join":  strings.Join,
+	}).Parse(text)
+}
+
+// joinPortLists is the template "ports" function: the ports of one or more
+// lists, such as {{ports .RequiredPresent .AnyOfPresent}}, as one list.
+func joinPortLists(lists ...[]nsight.Port) string {
+	var all []nsight.Port
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return portList(all)
+}
+
+// printTemplate renders each finding through tmpl.
+func printTemplate(findings []finding, tmpl *template.Template) error {
+	for _, f := range findings {
+		if err := tmpl.Execute(stdout, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
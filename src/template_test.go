@@ -0,0 +1,18 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOnelineTemplateAnyOfPorts(t *testing.T) {
+	_, out, errOut := runCLI(t, "--template", "oneline", "testdata/dc.nmap")
+	for _, want := range []string{
+		"10.0.0.5: Windows remote-admin surface [135, 445, 5985]\n",
+		"10.0.0.5: SNMP agent [161/udp]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in\n%s%s", want, out, errOut)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+// tuiEntry is one row of the --tui host list.
+type tuiEntry struct {
+	name    string
+	host    *nsight.Host
+	matches []nsight.Result
+}
+
+// tui is the state of the --tui browser: a host list on the left, the
+// selected host's matches on the right and, on demand, the explanation of one
+// match in place of both.
+type tui struct {
+	entries      []tuiEntry
+	sigs         map[string]nsight.Signature
+	host, match  int  // selected rows
+	onMatches    bool // focus is on the right-hand pane
+	detail       bool // showing the explanation of the selected match
+	rows, cols   int
+	hostColWidth int
+}
+
+// canRunTUI reports whether --tui can take over the terminal. When it can't,
+// for example because output is piped, nsight prints its normal report.
+func canRunTUI() bool {
+	return outputPath == "" && isTerminal(os.Stdin) && isTerminal(stdout)
+}
+
+// runTUI scans paths and lets the user browse the results until they quit.
+// It returns the exit code analyse would have.
+func runTUI(paths []string, sigs []nsight.Signature, cfg runConfig) int {
+	entries, code := tuiEntries(paths, sigs, cfg)
+	if len(entries) == 0 {
+		if code != exitError {
+			warnf("no hosts to show")
+		}
+		return code
+	}
+
+	t := &tui{entries: entries, sigs: make(map[string]nsight.Signature, len(sigs))}
+	for _, sig := range sigs {
+		t.sigs[sig.Name] = sig
+	}
+	t.rows, t.cols = terminalSize()
+	for _, e := range entries {
+		t.hostColWidth = max(t.hostColWidth, utf8.RuneCountInString(e.name))
+	}
+	t.hostColWidth = min(t.hostColWidth, t.cols/3)
+
+	restore, err := cbreak()
+	if err != nil {
+		warnf("--tui: cannot set up the terminal: %v", err)
+		return code
+	}
+	// Ctrl-C still raises SIGINT in cbreak mode; treat it like q so the
+	// terminal is always put back.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	keys := make(chan string)
+	go readKeys(keys)
+	fmt.Fprint(stdout, "\033[?1049h\033[?25l")
+	defer func() {
+		signal.Stop(interrupt)
+		fmt.Fprint(stdout, "\033[?25h\033[?1049l")
+		restore()
+	}()
+	for {
+		t.draw()
+		select {
+		case <-interrupt:
+			return code
+		case key, ok := <-keys:
+			if !ok || !t.handle(key) {
+				return code
+			}
+		}
+	}
+}
+
+// tuiEntries scans paths into the --tui host list, applying --baseline and
+// --merge as the report does, and returns it with the exit code analyse
+// would give, --fail-on included.
+func tuiEntries(paths []string, sigs []nsight.Signature, cfg runConfig) ([]tuiEntry, int) {
+	match := func(h *nsight.Host) []nsight.Result { return matchHost(h, sigs, cfg.minConfidence) }
+	perFile := match
+	if cfg.merge {
+		perFile = nil // only the merged host is matched
+	}
+	var entries []tuiEntry
+	merged := nsight.NewHost()
+	parsed := 0
+	for i, s := range scanAll(paths, cfg.jobs, perFile) {
+		if s.err != nil {
+			warnf("cannot parse %s: %v", paths[i], s.err)
+			continue
+		}
+		parsed++
+		for _, host := range nsight.SortedHosts(s.hosts) {
+			if cfg.merge {
+				merged.Merge(s.hosts[host])
+				continue
+			}
+			name := host
+			if name == "" {
+				name = "(no address)"
+			}
+			if len(paths) > 1 {
+				name = paths[i] + " " + name
+			}
+			entries = append(entries, tuiEntry{name, s.hosts[host], s.matches[host]})
+		}
+	}
+	if parsed == 0 {
+		return nil, exitError
+	}
+	if cfg.merge {
+		entries = []tuiEntry{{"all hosts", merged, approved.matches("", match(merged))}}
+	}
+	code := exitNoMatch
+	for _, e := range entries {
+		if cfg.gate.tripped(e.matches) {
+			return entries, exitFailOn
+		}
+		if len(e.matches) > 0 {
+			code = exitMatch
+		}
+	}
+	return entries, code
+}
+
+// handle applies one key press, returning false when the user quits.
+func (t *tui) handle(key string) bool {
+	if t.detail {
+		t.detail = false // any key returns to the lists
+		return key != "q"
+	}
+	matches := t.entries[t.host].matches
+	switch key {
+	case "q", "\x1b":
+		return false
+	case "\x1b[A", "k":
+		if t.onMatches {
+			t.match = max(0, t.match-1)
+		} else if t.host > 0 {
+			t.host, t.match = t.host-1, 0
+		}
+	case "\x1b[B", "j":
+		if t.onMatches {
+			t.match = min(max(0, len(matches)-1), t.match+1)
+		} else if t.host < len(t.entries)-1 {
+			t.host, t.match = t.host+1, 0
+		}
+	case "\x1b[C", "l", "\t":
+		t.onMatches = len(matches) > 0
+	case "\x1b[D", "h":
+		t.onMatches = false
+	case "\r", "\n":
+		if t.onMatches {
+			t.detail = true
+		} else {
+			t.onMatches = len(matches) > 0
+		}
+	}
+	return true
+}
+
+func (t *tui) draw() {
+	fmt.Fprint(stdout, "\033[H\033[2J")
+	if t.detail {
+		t.drawDetail()
+		return
+	}
+	entry := t.entries[t.host]
+	right := make([]string, len(entry.matches))
+	for i, m := range entry.matches {
+		// Truncate before styling so escape codes don't count towards the width.
+		text := truncate(fmt.Sprintf("%s (%s, %.2f)", m.Signature, m.Severity, m.Confidence), t.cols-t.hostColWidth-5)
+		if t.onMatches && i == t.match {
+			text = "\033[7m" + text + reset
+		}
+		right[i] = style("▶", severityColour(m.Severity), true, false) + " " + text
+	}
+	if len(right) == 0 {
+		right = []string{style("No composite service signatures recognised.", yellow, false, false)}
+	}
+	height := t.rows - 2
+	top := max(0, t.host-height+1)
+	rightTop := max(0, t.match-height+1)
+	fmt.Fprintln(stdout, style(fmt.Sprintf("%-*s", t.hostColWidth, "HOSTS"), "", true, false)+" │ "+style("MATCHES", "", true, false))
+	for row := 0; row < height; row++ {
+		left := ""
+		if i := top + row; i < len(t.entries) {
+			left = fmt.Sprintf("%-*s", t.hostColWidth, truncate(t.entries[i].name, t.hostColWidth))
+			switch {
+			case i == t.host && t.onMatches:
+				left = bold + left + reset
+			case i == t.host:
+				left = "\033[7m" + left + reset
+			}
+		} else {
+			left = strings.Repeat(" ", t.hostColWidth)
+		}
+		line := left + " │"
+		if i := rightTop + row; i < len(right) {
+			line += " " + right[i]
+		}
+		fmt.Fprintln(stdout, line)
+	}
+	fmt.Fprint(stdout, style("↑/↓ move  ←/→ switch pane  enter explain  q quit", "", false, true))
+}
+
+// drawDetail shows the --explain trace for the selected match.
+func (t *tui) drawDetail() {
+	entry := t.entries[t.host]
+	m := entry.matches[t.match]
+	fmt.Fprintln(stdout, style(entry.name, "", true, false))
+	fmt.Fprintln(stdout)
+	if sig, ok := t.sigs[m.Signature]; ok {
+		explainSignature(entry.host, nsight.Explain(entry.host, sig))
+	} else {
+		fmt.Fprintf(stdout, "  %s was reported by a detector, not a port signature.\n", m.Signature)
+	}
+	if showBanners {
+		printBanners(m)
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprint(stdout, style("any key to go back, q to quit", "", false, true))
+}
+
+// truncate shortens s to n runes, marking the cut with "…".
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n || n < 1 {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// readKeys sends each key press (or escape sequence) read from stdin on keys,
+// closing it when stdin ends.
+func readKeys(keys chan<- string) {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		keys <- string(buf[:n])
+	}
+}
+
+// cbreak turns off line buffering and echo on the terminal through stty,
+// returning a function that restores the previous settings.
+func cbreak() (restore func(), err error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+// terminalSize returns the terminal's rows and columns, or 24x80 if stty
+// can't tell.
+func terminalSize() (rows, cols int) {
+	out, err := stty("size")
+	if fields := strings.Fields(out); err == nil && len(fields) == 2 {
+		r, errR := strconv.Atoi(fields[0])
+		c, errC := strconv.Atoi(fields[1])
+		if errR == nil && errC == nil && r > 2 && c > 0 {
+			return r, c
+		}
+	}
+	return 24, 80
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
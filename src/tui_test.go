@@ -0,0 +1,68 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/raffaele-99/nsight/pkg/nsight"
+)
+
+func TestTUIEntriesGateAndBaseline(t *testing.T) {
+	defer func(b baseline) { approved = b }(approved)
+	sigs := nsight.Signatures()
+	redis := failGate{name: regexp.MustCompile("(?i)redis")}
+	tests := []struct {
+		name     string
+		cfg      runConfig
+		baseline []string
+		want     int
+	}{
+		{"no gate", runConfig{jobs: 1}, nil, exitMatch},
+		{"gate", runConfig{jobs: 1, gate: redis}, nil, exitFailOn},
+		{"gate, baselined", runConfig{jobs: 1, gate: redis}, []string{"10.0.0.9:Redis"}, exitMatch},
+		{"merged gate", runConfig{jobs: 1, gate: redis, merge: true}, nil, exitFailOn},
+		{"merged gate, baselined", runConfig{jobs: 1, gate: redis, merge: true}, []string{"*:Redis"}, exitMatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approved = nil
+			for _, line := range tt.baseline {
+				e, err := parseBaselineEntry(line)
+				if err != nil {
+					t.Fatal(err)
+				}
+				approved = append(approved, e)
+			}
+			entries, code := tuiEntries([]string{"testdata/dc.nmap"}, sigs, tt.cfg)
+			if code != tt.want {
+				t.Errorf("exit %d, want %d", code, tt.want)
+			}
+			for _, e := range entries {
+				for _, m := range e.matches {
+					if len(tt.baseline) > 0 && m.Signature == "Redis" {
+						t.Errorf("%s: baselined Redis still listed", e.name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"dc01.corp.local", 20, "dc01.corp.local"},
+		{"dc01.corp.local", 5, "dc01…"},
+		{"héllo wörld", 6, "héllo…"},
+		{"日本語のホスト", 4, "日本語…"},
+		{"abc", 0, "abc"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
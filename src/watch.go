@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// watchInterval is how often --watch polls the scan files. A change is only
+// rendered once the files have stayed the same for a whole interval, so a
+// burst of writes from nmap causes one redraw.
+const watchInterval = 500 * time.Millisecond
+
+// fileState is what --watch compares between polls.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watchFiles renders once, then again after each change to paths, clearing
+// the screen (or the --output file) in between, until interrupted. It returns
+// the last exit code.
+func watchFiles(paths []string, render func() int) int {
+	for _, path := range paths {
+		if path == "-" || strings.Contains(path, "://") {
+			errorf("--watch needs local files, not stdin or URLs")
+			return exitError
+		}
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	last := statFiles(paths)
+	clearScreen()
+	code := render()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	pending := false
+	for {
+		select {
+		case <-interrupt:
+			return code
+		case <-ticker.C:
+			cur := statFiles(paths)
+			if !sameStates(cur, last) {
+				last, pending = cur, true
+				continue
+			}
+			if pending {
+				pending = false
+				clearScreen()
+				code = render()
+			}
+		}
+	}
+}
+
+// statFiles records the size and modification time of each path; a missing
+// file gets the zero state.
+func statFiles(paths []string) []fileState {
+	out := make([]fileState, len(paths))
+	for i, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			out[i] = fileState{fi.Size(), fi.ModTime()}
+		}
+	}
+	return out
+}
+
+func sameStates(a, b []fileState) bool {
+	for i := range a {
+		if a[i].size != b[i].size || !a[i].modTime.Equal(b[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// clearScreen makes way for the next render: it clears the terminal, or
+// with --output empties the file. Output piped elsewhere is left alone, so
+// each render follows the last without escape codes in the way.
+func clearScreen() {
+	if f, ok := stdout.(*os.File); ok && outputPath != "" {
+		f.Truncate(0)
+		f.Seek(0, io.SeekStart)
+		return
+	}
+	if isTerminal(stdout) {
+		fmt.Fprint(stdout, "\033[H\033[2J")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClearScreenOffTerminal(t *testing.T) {
+	defer func(out io.Writer, path string) { stdout, outputPath = out, path }(stdout, outputPath)
+	var buf bytes.Buffer
+	stdout, outputPath = &buf, ""
+	buf.WriteString(`{"findings": []}` + "\n")
+	clearScreen()
+	if got := buf.String(); got != `{"findings": []}`+"\n" {
+		t.Errorf("clearScreen wrote to piped output: %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdout, outputPath = f, path
+	f.WriteString("old report\n")
+	clearScreen()
+	f.WriteString("new\n")
+	if data, _ := os.ReadFile(path); string(data) != "new\n" {
+		t.Errorf("--output file holds %q after clearScreen", data)
+	}
+}